@@ -0,0 +1,93 @@
+/*----------------------------------------------------------------
+ *  Copyright (c) ThoughtWorks, Inc.
+ *  Licensed under the Apache License, Version 2.0
+ *  See LICENSE in the project root for license information.
+ *----------------------------------------------------------------*/
+
+package builder
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/getgauge/xml-report/model"
+)
+
+// Values reported in place of volatile attributes in deterministic mode.
+const (
+	deterministicHostname = "localhost"
+	deterministicTime     = "0.000"
+)
+
+var deterministicTimestamp = formatTimestamp(time.Unix(0, 0).UTC())
+
+// makeDeterministic orders the report independently of the execution order
+// and replaces the attributes which change on every run, so that two reports
+// of the same specs can be diffed. Suites are sorted by spec file, with the
+// suite hooks last, and testcases by their line in the spec.
+func (x *XmlBuilder) makeDeterministic() {
+	suites := x.suites.Suites
+	sort.SliceStable(suites, func(i, j int) bool {
+		if suites[i].Package == "" || suites[j].Package == "" {
+			return suites[j].Package == "" && suites[i].Package != ""
+		}
+		return suites[i].Package < suites[j].Package
+	})
+	x.suites.Time = deterministicTime
+	x.suites.Timestamp = deterministicTimestamp
+	for i := range suites {
+		ts := &suites[i]
+		ts.Id = i + 1
+		ts.Time = deterministicTime
+		ts.Timestamp = deterministicTimestamp
+		ts.Hostname = deterministicHostname
+		sortTestCases(ts.TestCases)
+		for j := range ts.TestCases {
+			ts.TestCases[j].Time = deterministicTime
+		}
+	}
+}
+
+// sortTestCases sorts testcases by line. Testcases without a line, such as
+// spec hooks, stay before or after the others as they were.
+func sortTestCases(testCases []JUnitTestCase) {
+	lines := make([]int64, len(testCases))
+	noLine := int64(0)
+	for i, testCase := range testCases {
+		if testCase.Line == 0 {
+			lines[i] = noLine
+		} else {
+			lines[i] = testCase.Line
+			noLine = math.MaxInt64
+		}
+	}
+	sort.Stable(testCasesByLine{testCases, lines})
+}
+
+type testCasesByLine struct {
+	testCases []JUnitTestCase
+	lines     []int64
+}
+
+func (t testCasesByLine) Len() int           { return len(t.testCases) }
+func (t testCasesByLine) Less(i, j int) bool { return t.lines[i] < t.lines[j] }
+func (t testCasesByLine) Swap(i, j int) {
+	t.testCases[i], t.testCases[j] = t.testCases[j], t.testCases[i]
+	t.lines[i], t.lines[j] = t.lines[j], t.lines[i]
+}
+
+// sortSpecs returns the specs sorted by spec file, each with its scenarios
+// sorted by line, for the formats written from the model. The report is
+// shared by all formats, so it is left as it is.
+func sortSpecs(specs []*model.Spec) []*model.Spec {
+	sorted := make([]*model.Spec, len(specs))
+	for i, spec := range specs {
+		s := *spec
+		s.Scenarios = append([]*model.Scenario{}, spec.Scenarios...)
+		sort.SliceStable(s.Scenarios, func(i, j int) bool { return s.Scenarios[i].Line < s.Scenarios[j].Line })
+		sorted[i] = &s
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].File < sorted[j].File })
+	return sorted
+}
@@ -0,0 +1,92 @@
+/*----------------------------------------------------------------
+ *  Copyright (c) ThoughtWorks, Inc.
+ *  Licensed under the Apache License, Version 2.0
+ *  See LICENSE in the project root for license information.
+ *----------------------------------------------------------------*/
+
+package builder
+
+import (
+	"encoding/xml"
+
+	"github.com/getgauge/gauge-proto/go/gauge_messages"
+	. "gopkg.in/check.v1"
+)
+
+func newDeterministicSpecResult(file string, executionTime int64, scenarios ...*gauge_messages.ProtoScenario) *gauge_messages.ProtoSpecResult {
+	var items []*gauge_messages.ProtoItem
+	for _, scenario := range scenarios {
+		items = append(items, &gauge_messages.ProtoItem{Scenario: scenario, ItemType: gauge_messages.ProtoItem_Scenario})
+	}
+	spec := &gauge_messages.ProtoSpec{SpecHeading: file, FileName: file, Items: items}
+	return &gauge_messages.ProtoSpecResult{ProtoSpec: spec, ScenarioCount: int32(len(scenarios)), ExecutionTime: executionTime}
+}
+
+func newDeterministicScenario(heading string, line, executionTime int64) *gauge_messages.ProtoScenario {
+	return &gauge_messages.ProtoScenario{ScenarioHeading: heading, ExecutionStatus: gauge_messages.ExecutionStatus_PASSED,
+		Span: &gauge_messages.Span{Start: line, End: line + 1}, ExecutionTime: executionTime}
+}
+
+func (s *MySuite) TestDeterministicReportsAreIndependentOfExecution(c *C) {
+	first := &gauge_messages.SuiteExecutionResult{SuiteResult: &gauge_messages.ProtoSuiteResult{
+		TimestampISO: "2021-01-01T10:00:00Z", ExecutionTime: 30,
+		SpecResults: []*gauge_messages.ProtoSpecResult{
+			newDeterministicSpecResult("specs/b.spec", 10, newDeterministicScenario("B2", 8, 4), newDeterministicScenario("B1", 3, 6)),
+			newDeterministicSpecResult("specs/a.spec", 20, newDeterministicScenario("A1", 2, 20)),
+		},
+	}}
+	second := &gauge_messages.SuiteExecutionResult{SuiteResult: &gauge_messages.ProtoSuiteResult{
+		TimestampISO: "2021-06-01T12:30:00Z", ExecutionTime: 45,
+		SpecResults: []*gauge_messages.ProtoSpecResult{
+			newDeterministicSpecResult("specs/a.spec", 25, newDeterministicScenario("A1", 2, 25)),
+			newDeterministicSpecResult("specs/b.spec", 20, newDeterministicScenario("B1", 3, 12), newDeterministicScenario("B2", 8, 8)),
+		},
+	}}
+
+	firstBytes, err := NewXmlBuilder(0, Config{Deterministic: true}).GetXmlContent(first)
+	c.Assert(err, Equals, nil)
+	secondBytes, err := NewXmlBuilder(5, Config{Deterministic: true}).GetXmlContent(second)
+	c.Assert(err, Equals, nil)
+
+	c.Assert(string(firstBytes), Equals, string(secondBytes))
+
+	var suites JUnitTestSuites
+	c.Assert(xml.Unmarshal(firstBytes, &suites), Equals, nil)
+
+	c.Assert(suites.Timestamp, Equals, "1970-01-01T00:00:00Z")
+	c.Assert(suites.Suites[0].Id, Equals, 1)
+	c.Assert(suites.Suites[0].Package, Equals, "specs/a.spec")
+	c.Assert(suites.Suites[0].Hostname, Equals, "localhost")
+	c.Assert(suites.Suites[1].Id, Equals, 2)
+	c.Assert(suites.Suites[1].TestCases[0].Name, Equals, "B1")
+	c.Assert(suites.Suites[1].TestCases[1].Name, Equals, "B2")
+	c.Assert(suites.Suites[1].TestCases[1].Time, Equals, "0.000")
+}
+
+func (s *MySuite) TestDeterministicReportsKeepSuiteHooksLast(c *C) {
+	message := &gauge_messages.SuiteExecutionResult{SuiteResult: &gauge_messages.ProtoSuiteResult{
+		PreHookFailure: &gauge_messages.ProtoHookFailure{ErrorMessage: "failed"},
+		SpecResults:    []*gauge_messages.ProtoSpecResult{newDeterministicSpecResult("specs/a.spec", 0)},
+	}}
+
+	bytes, err := NewXmlBuilder(0, Config{Deterministic: true}).GetXmlContent(message)
+	c.Assert(err, Equals, nil)
+
+	var suites JUnitTestSuites
+	c.Assert(xml.Unmarshal(bytes, &suites), Equals, nil)
+
+	c.Assert(suites.Suites[0].Package, Equals, "specs/a.spec")
+	c.Assert(suites.Suites[1].Name, Equals, "Suite Hooks")
+}
+
+func (s *MySuite) TestSortTestCasesKeepsCasesWithoutLineInPlace(c *C) {
+	testCases := []JUnitTestCase{{Name: "BeforeSpec"}, {Name: "Second", Line: 9}, {Name: "First", Line: 4}, {Name: "AfterSpec"}}
+
+	sortTestCases(testCases)
+
+	var names []string
+	for _, testCase := range testCases {
+		names = append(names, testCase.Name)
+	}
+	c.Assert(names, DeepEquals, []string{"BeforeSpec", "First", "Second", "AfterSpec"})
+}
@@ -0,0 +1,100 @@
+/*----------------------------------------------------------------
+ *  Copyright (c) ThoughtWorks, Inc.
+ *  Licensed under the Apache License, Version 2.0
+ *  See LICENSE in the project root for license information.
+ *----------------------------------------------------------------*/
+
+package builder
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/getgauge/xml-report/model"
+)
+
+// JUnitFormat is the name of the JUnit XML format, which is the default.
+const JUnitFormat = "junit"
+
+// Encoder writes the result of a suite execution in a report format.
+type Encoder interface {
+	// Name is the name the format is selected by, e.g. "junit".
+	Name() string
+	// FileExtension is appended to "result" to name the report file, e.g.
+	// ".xml". It is unique among the registered formats.
+	FileExtension() string
+	// Encode returns the report of the suite.
+	Encode(*model.Report) ([]byte, error)
+	// Attachments returns the screenshot files referenced by the last report
+	// and where they are copied to.
+	Attachments() []Attachment
+}
+
+// Attachment is a screenshot file referenced by a report.
+type Attachment struct {
+	// File is the screenshot, relative to the Gauge screenshots directory
+	// unless absolute.
+	File string
+	// Path is where the report expects the copy of File, relative to the
+	// report directory and using forward slashes, e.g. AttachmentPath(File).
+	Path string
+}
+
+// EncoderFactory creates an Encoder using the report settings.
+type EncoderFactory func(config Config) Encoder
+
+var encoders = map[string]EncoderFactory{}
+
+// extensions maps the file extension of each registered format to its name.
+var extensions = map[string]string{}
+
+func init() {
+	RegisterEncoder(JUnitFormat, func(config Config) Encoder { return NewXmlBuilder(0, config) })
+	RegisterEncoder(NUnitFormat, func(config Config) Encoder { return NewNUnitBuilder(config) })
+	RegisterEncoder(TrxFormat, func(config Config) Encoder { return NewTrxBuilder(config) })
+	RegisterEncoder(XUnitFormat, func(config Config) Encoder { return NewXUnitBuilder(config) })
+}
+
+// RegisterEncoder makes a format available to NewEncoder. It panics if a
+// format is registered twice, or if its file extension is already used by
+// another format.
+func RegisterEncoder(name string, factory EncoderFactory) {
+	if _, ok := encoders[name]; ok {
+		panic(fmt.Sprintf("report format %s is already registered", name))
+	}
+	extension := factory(Config{}).FileExtension()
+	if other, ok := extensions[extension]; ok {
+		panic(fmt.Sprintf("report format %s uses the file extension %s of report format %s", name, extension, other))
+	}
+	encoders[name] = factory
+	extensions[extension] = name
+}
+
+// NewEncoder creates the encoder of the named format.
+func NewEncoder(name string, config Config) (Encoder, error) {
+	factory, ok := encoders[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown report format %s, expected one of %v", name, Formats())
+	}
+	return factory(config), nil
+}
+
+// Formats returns the names of the registered formats, sorted.
+func Formats() []string {
+	var names []string
+	for name := range encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Name returns JUnitFormat.
+func (x *XmlBuilder) Name() string {
+	return JUnitFormat
+}
+
+// FileExtension returns ".xml", so that the JUnit report stays result.xml.
+func (x *XmlBuilder) FileExtension() string {
+	return ".xml"
+}
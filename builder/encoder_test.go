@@ -0,0 +1,53 @@
+/*----------------------------------------------------------------
+ *  Copyright (c) ThoughtWorks, Inc.
+ *  Licensed under the Apache License, Version 2.0
+ *  See LICENSE in the project root for license information.
+ *----------------------------------------------------------------*/
+
+package builder
+
+import (
+	"github.com/getgauge/gauge-proto/go/gauge_messages"
+	"github.com/getgauge/xml-report/model"
+	. "gopkg.in/check.v1"
+)
+
+func (s *MySuite) TestNewEncoderForJUnit(c *C) {
+	encoder, err := NewEncoder(JUnitFormat, Config{})
+
+	c.Assert(err, Equals, nil)
+	c.Assert(encoder.Name(), Equals, "junit")
+	c.Assert(encoder.FileExtension(), Equals, ".xml")
+}
+
+func (s *MySuite) TestNewEncoderForUnknownFormat(c *C) {
+	_, err := NewEncoder("unknown", Config{})
+
+	c.Assert(err, ErrorMatches, "unknown report format unknown, expected one of .*junit.*")
+}
+
+func (s *MySuite) TestJUnitEncoderWritesXmlContent(c *C) {
+	spec := &gauge_messages.ProtoSpec{SpecHeading: "HEADING", FileName: "FILENAME"}
+	message := &gauge_messages.SuiteExecutionResult{SuiteResult: &gauge_messages.ProtoSuiteResult{
+		SpecResults: []*gauge_messages.ProtoSpecResult{{ProtoSpec: spec}},
+	}}
+	encoder, _ := NewEncoder(JUnitFormat, Config{Deterministic: true})
+
+	bytes, err := encoder.Encode(model.NewReport(message, model.Options{}))
+	want, _ := NewXmlBuilder(0, Config{Deterministic: true}).GetXmlContent(message)
+
+	c.Assert(err, Equals, nil)
+	c.Assert(string(bytes), Equals, string(want))
+}
+
+func (s *MySuite) TestRegisterEncoderTwicePanics(c *C) {
+	c.Assert(func() { RegisterEncoder(JUnitFormat, nil) }, PanicMatches, "report format junit is already registered")
+}
+
+func (s *MySuite) TestRegisterEncoderWithUsedFileExtensionPanics(c *C) {
+	factory := func(config Config) Encoder { return NewXmlBuilder(0, config) }
+
+	c.Assert(func() { RegisterEncoder("junit2", factory) }, PanicMatches, "report format junit2 uses the file extension .xml of report format junit")
+	_, err := NewEncoder("junit2", Config{})
+	c.Assert(err, NotNil)
+}
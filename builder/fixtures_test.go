@@ -0,0 +1,126 @@
+/*----------------------------------------------------------------
+ *  Copyright (c) ThoughtWorks, Inc.
+ *  Licensed under the Apache License, Version 2.0
+ *  See LICENSE in the project root for license information.
+ *----------------------------------------------------------------*/
+
+package builder
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/getgauge/xml-report/model"
+	. "gopkg.in/check.v1"
+)
+
+var checkoutStartTime = time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+// newCheckoutReport returns a spec with a passed, a failed, a skipped and an
+// errored scenario, which every format reports.
+func newCheckoutReport() *model.Report {
+	return &model.Report{ProjectName: "shop", Environment: "ci", StartTime: checkoutStartTime, Duration: 3 * time.Second,
+		Specs: []*model.Spec{{
+			Name: "Checkout", File: "specs/checkout.spec", Tags: []string{"checkout"}, StartTime: checkoutStartTime, Duration: 2 * time.Second,
+			Scenarios: []*model.Scenario{
+				{Name: "Pay", Heading: "Pay", Line: 4, Status: model.Passed, Tags: []string{"smoke", "checkout"}, Duration: 1500 * time.Millisecond},
+				{Name: "Refund", Heading: "Refund", Line: 9, Status: model.Failed, Failures: []model.Failure{{Message: "boom", StackTrace: "trace"}}},
+				{Name: "Cancel", Heading: "Cancel", Line: 14, Status: model.Skipped, SkipReasons: []string{"not ready"}},
+				{Name: "Ship", Heading: "Ship", Line: 18, Status: model.Failed, Failures: []model.Failure{{Message: "hook", Errored: true}}},
+			},
+		}},
+	}
+}
+
+// newOutputReport returns a scenario whose messages need sanitizing and break
+// out of CDATA, around a screenshot.
+func newOutputReport() *model.Report {
+	return &model.Report{Specs: []*model.Spec{{
+		Name: "Payment",
+		Scenarios: []*model.Scenario{{
+			Name: "Pay", Heading: "Pay", Status: model.Passed,
+			Output: []model.Output{{Message: "paid\x1b[31m ]]> done"}, {Screenshot: "paid.png"}, {Message: "logged out"}},
+		}},
+	}}}
+}
+
+// newTruncatedReport returns a failure whose message and stack trace are
+// longer than truncatedFieldSize, with characters to sanitize.
+func newTruncatedReport() *model.Report {
+	return &model.Report{Specs: []*model.Spec{{
+		Name: "Payment",
+		Scenarios: []*model.Scenario{{
+			Name: "Pay\x1b[31m", Heading: "Pay", Status: model.Failed,
+			Failures: []model.Failure{{Message: strings.Repeat("m", 100), StackTrace: "\x00" + strings.Repeat("s", 100)}},
+		}},
+	}}}
+}
+
+const truncatedFieldSize = 20
+
+var (
+	truncatedMessage    = strings.Repeat("m", 20) + " ... [truncated 80 bytes]"
+	truncatedStackTrace = "[U+0000]" + strings.Repeat("s", 2) + "\n... [truncated 88 bytes] ...\n" + strings.Repeat("s", 10)
+)
+
+// newOversizedReport returns failures whose stack traces and output are made
+// of characters which XML escapes or CDATA splits, far beyond oversizedFileSize.
+func newOversizedReport() *model.Report {
+	var scenarios []*model.Scenario
+	for i := 0; i < 10; i++ {
+		scenarios = append(scenarios, &model.Scenario{
+			Name: fmt.Sprintf("Scenario %d", i), Heading: fmt.Sprintf("Scenario %d", i), Status: model.Failed,
+			Failures: []model.Failure{{Message: "expected <&>", StackTrace: strings.Repeat("at <step> & ]]>\n", 500)}},
+			Output:   []model.Output{{Message: strings.Repeat("<&>]]>\n", 300)}},
+		})
+	}
+	return &model.Report{Specs: []*model.Spec{{Name: "Payment", File: "specs/payment.spec", Scenarios: scenarios}}}
+}
+
+const oversizedFileSize = 20000
+
+// assertWithinOversizedFileSize asserts that the report fits in
+// oversizedFileSize without truncating much more than needed.
+func assertWithinOversizedFileSize(c *C, bytes []byte) {
+	c.Assert(len(bytes) <= oversizedFileSize, Equals, true, Commentf("report is %d bytes", len(bytes)))
+	c.Assert(len(bytes) > oversizedFileSize*9/10, Equals, true, Commentf("report is %d bytes", len(bytes)))
+}
+
+// newReorderedReports returns two runs of the same specs, which differ only
+// in execution order, time and durations.
+func newReorderedReports() (*model.Report, *model.Report) {
+	spec := func(file string, duration time.Duration, scenarios ...*model.Scenario) *model.Spec {
+		return &model.Spec{Name: file, File: file, Duration: duration, Scenarios: scenarios}
+	}
+	scenario := func(heading string, line int64, duration time.Duration) *model.Scenario {
+		return &model.Scenario{Name: heading, Heading: heading, Line: line, Duration: duration}
+	}
+	first := &model.Report{StartTime: time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC), Duration: time.Second, Specs: []*model.Spec{
+		spec("specs/b.spec", time.Second, scenario("B2", 8, time.Second), scenario("B1", 3, time.Second)),
+		spec("specs/a.spec", time.Second, scenario("A1", 2, time.Second)),
+	}}
+	second := &model.Report{StartTime: time.Date(2021, 6, 1, 12, 30, 0, 0, time.UTC), Duration: 2 * time.Second, Specs: []*model.Spec{
+		spec("specs/a.spec", 2*time.Second, scenario("A1", 2, 2*time.Second)),
+		spec("specs/b.spec", 2*time.Second, scenario("B1", 3, time.Second), scenario("B2", 8, time.Second)),
+	}}
+	return first, second
+}
+
+// encodeReorderedReports encodes both reordered reports in deterministic mode,
+// asserts they are identical and returns the first.
+func encodeReorderedReports(c *C, format string) []byte {
+	first, second := newReorderedReports()
+	var reports []string
+	for _, report := range []*model.Report{first, second} {
+		encoder, err := NewEncoder(format, Config{Deterministic: true})
+		c.Assert(err, Equals, nil)
+		bytes, err := encoder.Encode(report)
+		c.Assert(err, Equals, nil)
+		reports = append(reports, string(bytes))
+	}
+	c.Assert(reports[0], Equals, reports[1])
+	c.Assert(first.Specs[0].File, Equals, "specs/b.spec")
+	c.Assert(first.Specs[0].Scenarios[0].Name, Equals, "B2")
+	return []byte(reports[0])
+}
@@ -11,10 +11,12 @@ package builder
 
 import (
 	"encoding/xml"
+	"fmt"
 	"os"
 	"path/filepath"
 
 	"strings"
+	"time"
 
 	"github.com/getgauge/gauge-proto/go/gauge_messages"
 	"github.com/lestrrat-go/libxml2"
@@ -196,36 +198,6 @@ very easy to trace`
 	c.Assert(suites.Suites[0].TestCases[0].Failure.Contents, Equals, failure)
 }
 
-func (s *MySuite) TestToVerifyXmlContentForFailingHookExecutionResult(c *C) {
-	builder := &XmlBuilder{currentId: 0}
-	info := builder.getFailureFromExecutionResult("", nil, nil, nil, "PREFIX ")
-
-	c.Assert(info.Message, Equals, "")
-	c.Assert(info.Err, Equals, "")
-
-	failure := &gauge_messages.ProtoHookFailure{StackTrace: "StackTrace", ErrorMessage: "ErrorMessage"}
-	hookInfo := builder.getFailureFromExecutionResult("", failure, nil, nil, "PREFIX ")
-
-	c.Assert(hookInfo.Message, Equals, "PREFIX "+preHookFailureMsg+": 'ErrorMessage'")
-	c.Assert(hookInfo.Err, Equals, "StackTrace")
-
-	hookInfo = builder.getFailureFromExecutionResult("", nil, failure, nil, "PREFIX ")
-
-	c.Assert(hookInfo.Message, Equals, "PREFIX "+postHookFailureMsg+": 'ErrorMessage'")
-	c.Assert(hookInfo.Err, Equals, "StackTrace")
-
-	hookInfo = builder.getFailureFromExecutionResult("Foo", nil, failure, nil, "PREFIX ")
-
-	c.Assert(hookInfo.Message, Equals, "Foo\nPREFIX "+postHookFailureMsg+": 'ErrorMessage'")
-	c.Assert(hookInfo.Err, Equals, "StackTrace")
-
-	executionFailure := &gauge_messages.ProtoExecutionResult{StackTrace: "StackTrace", ErrorMessage: "ErrorMessage", Failed: true}
-	execInfo := builder.getFailureFromExecutionResult("Foo", nil, nil, executionFailure, "PREFIX ")
-
-	c.Assert(execInfo.Message, Equals, "Foo\nPREFIX "+executionFailureMsg+": 'ErrorMessage'")
-	c.Assert(execInfo.Err, Equals, "StackTrace")
-}
-
 func (s *MySuite) TestToVerifyXmlContentForDataTableDrivenExecution(c *C) {
 	tableItem := &gauge_messages.ProtoItem{
 		ItemType: gauge_messages.ProtoItem_Table,
@@ -428,13 +400,709 @@ func (s *MySuite) TestToVerifyXmlContentForErroredSpec(c *C) {
 	xml.Unmarshal(bytes, &suites)
 
 	c.Assert(err, Equals, nil)
-	c.Assert(*suites.Suites[0].TestCases[0].Failure, Equals, JUnitFailure{
+	c.Assert(suites.Suites[0].Errors, Equals, 1)
+	c.Assert(suites.Suites[0].Failures, Equals, 0)
+	c.Assert(*suites.Suites[0].TestCases[0].Error, Equals, JUnitError{
 		Message:  "Parse/Validation Errors",
 		Type:     "Parse/Validation Errors",
 		Contents: "[Parse Error] message",
 	})
 }
 
+func (s *MySuite) TestToVerifyXmlContentForValidationErrors(c *C) {
+	scenType := gauge_messages.ProtoItem_Scenario
+	unimplemented := &gauge_messages.ProtoScenario{ScenarioHeading: "Unimplemented", ExecutionStatus: gauge_messages.ExecutionStatus_SKIPPED,
+		Span: &gauge_messages.Span{Start: 5, End: 8}, SkipErrors: []string{"Step implementation not found"}}
+	passing := &gauge_messages.ProtoScenario{ScenarioHeading: "Passing", ExecutionStatus: gauge_messages.ExecutionStatus_PASSED,
+		Span: &gauge_messages.Span{Start: 10, End: 12}}
+	items := []*gauge_messages.ProtoItem{{Scenario: unimplemented, ItemType: scenType}, {Scenario: passing, ItemType: scenType}}
+	spec := &gauge_messages.ProtoSpec{SpecHeading: "HEADING", FileName: filepath.Join("project", "specs", "example.spec"), Items: items}
+	errors := []*gauge_messages.Error{
+		{Type: gauge_messages.Error_VALIDATION_ERROR, Filename: filepath.Join("project", "specs", "example.spec"), LineNumber: 3, Message: "Context step not implemented"},
+		{Type: gauge_messages.Error_VALIDATION_ERROR, Filename: filepath.Join("project", "specs", "example.spec"), LineNumber: 6, Message: "Step implementation not found"},
+	}
+	specResult := &gauge_messages.ProtoSpecResult{ProtoSpec: spec, ScenarioCount: 2, ScenarioSkippedCount: 1, Errors: errors}
+	suiteResult := &gauge_messages.ProtoSuiteResult{SpecResults: []*gauge_messages.ProtoSpecResult{specResult}}
+	message := &gauge_messages.SuiteExecutionResult{SuiteResult: suiteResult}
+
+	builder := NewXmlBuilder(0, Config{ProjectRoot: "project"})
+	bytes, err := builder.GetXmlContent(message)
+
+	assertXmlValidation(bytes, c)
+
+	var suites JUnitTestSuites
+	xml.Unmarshal(bytes, &suites)
+
+	c.Assert(err, Equals, nil)
+	ts := suites.Suites[0]
+	c.Assert(ts.Tests, Equals, 3)
+	c.Assert(ts.Errors, Equals, 2)
+	c.Assert(ts.SkippedTestCount, Equals, 0)
+	c.Assert(len(ts.TestCases), Equals, 3)
+	c.Assert(ts.TestCases[0].Name, Equals, "HEADING")
+	c.Assert(ts.TestCases[0].Line, Equals, int64(3))
+	c.Assert(*ts.TestCases[0].Error, Equals, JUnitError{Message: "Validation Errors", Type: "Validation Errors",
+		Contents: "[Validation Error] specs/example.spec:3: Context step not implemented"})
+	c.Assert(ts.TestCases[1].Name, Equals, "Unimplemented")
+	c.Assert(ts.TestCases[1].SkipMessage, IsNil)
+	c.Assert(*ts.TestCases[1].Error, Equals, JUnitError{Message: "Validation Errors", Type: "Validation Errors",
+		Contents: "[Validation Error] specs/example.spec:6: Step implementation not found"})
+	c.Assert(ts.TestCases[2].Error, IsNil)
+}
+
+func (s *MySuite) TestToVerifyXmlContentForValidationErrorsInConceptFiles(c *C) {
+	scenario := &gauge_messages.ProtoScenario{ScenarioHeading: "Log in", ExecutionStatus: gauge_messages.ExecutionStatus_SKIPPED,
+		Span: &gauge_messages.Span{Start: 5, End: 50}}
+	spec := &gauge_messages.ProtoSpec{SpecHeading: "HEADING", FileName: filepath.Join("project", "specs", "example.spec"),
+		Items: []*gauge_messages.ProtoItem{{Scenario: scenario, ItemType: gauge_messages.ProtoItem_Scenario}}}
+	errors := []*gauge_messages.Error{
+		{Type: gauge_messages.Error_VALIDATION_ERROR, Filename: filepath.Join("project", "concepts", "login.cpt"), LineNumber: 42, Message: "Step implementation not found"},
+	}
+	specResult := &gauge_messages.ProtoSpecResult{ProtoSpec: spec, ScenarioCount: 1, ScenarioSkippedCount: 1, Errors: errors}
+	message := &gauge_messages.SuiteExecutionResult{SuiteResult: &gauge_messages.ProtoSuiteResult{SpecResults: []*gauge_messages.ProtoSpecResult{specResult}}}
+
+	bytes, err := NewXmlBuilder(0, Config{ProjectRoot: "project"}).GetXmlContent(message)
+
+	c.Assert(err, Equals, nil)
+	assertXmlValidation(bytes, c)
+	var suites JUnitTestSuites
+	c.Assert(xml.Unmarshal(bytes, &suites), Equals, nil)
+	testCase := suites.Suites[0].TestCases[0]
+	c.Assert(testCase.Name, Equals, "HEADING")
+	c.Assert(testCase.File, Equals, "concepts/login.cpt")
+	c.Assert(testCase.Line, Equals, int64(42))
+}
+
+func (s *MySuite) TestToVerifyXmlContentForScenarioMessages(c *C) {
+	stepType := gauge_messages.ProtoItem_Step
+	newStep := func(result *gauge_messages.ProtoExecutionResult) *gauge_messages.ProtoItem {
+		return &gauge_messages.ProtoItem{ItemType: stepType, Step: &gauge_messages.ProtoStep{StepExecutionResult: &gauge_messages.ProtoStepExecutionResult{ExecutionResult: result}}}
+	}
+	context := newStep(&gauge_messages.ProtoExecutionResult{Message: []string{"context message"}})
+	step := newStep(&gauge_messages.ProtoExecutionResult{Message: []string{"step message"}})
+	step.Step.PreHookMessages = []string{"before step"}
+	conceptStep := newStep(&gauge_messages.ProtoExecutionResult{Failed: true, ErrorMessage: "boom", StackTrace: "stacktrace", Message: []string{"concept message"}})
+	concept := &gauge_messages.ProtoItem{ItemType: gauge_messages.ProtoItem_Concept, Concept: &gauge_messages.ProtoConcept{Steps: []*gauge_messages.ProtoItem{conceptStep}}}
+	teardown := newStep(&gauge_messages.ProtoExecutionResult{Message: []string{"teardown message"}})
+
+	scenario := &gauge_messages.ProtoScenario{
+		ScenarioHeading:  "Scenario1",
+		ExecutionStatus:  gauge_messages.ExecutionStatus_FAILED,
+		PreHookMessages:  []string{"before scenario"},
+		Contexts:         []*gauge_messages.ProtoItem{context},
+		ScenarioItems:    []*gauge_messages.ProtoItem{step, concept},
+		TearDownSteps:    []*gauge_messages.ProtoItem{teardown},
+		PostHookMessages: []string{"after scenario"},
+	}
+	item := &gauge_messages.ProtoItem{Scenario: scenario, ItemType: gauge_messages.ProtoItem_Scenario}
+	spec := &gauge_messages.ProtoSpec{SpecHeading: "HEADING", FileName: "FILENAME", Items: []*gauge_messages.ProtoItem{item}}
+	specResult := &gauge_messages.ProtoSpecResult{ProtoSpec: spec, ScenarioCount: 1, Failed: true, ScenarioFailedCount: 1}
+	suiteResult := &gauge_messages.ProtoSuiteResult{SpecResults: []*gauge_messages.ProtoSpecResult{specResult}}
+	message := &gauge_messages.SuiteExecutionResult{SuiteResult: suiteResult}
+
+	builder := &XmlBuilder{currentId: 0}
+	bytes, err := builder.GetXmlContent(message)
+
+	assertXmlValidation(bytes, c)
+
+	var suites JUnitTestSuites
+	xml.Unmarshal(bytes, &suites)
+
+	c.Assert(err, Equals, nil)
+	testCase := suites.Suites[0].TestCases[0]
+	c.Assert(testCase.SystemOutput.Contents, Equals, "before scenario\ncontext message\nbefore step\nstep message\nconcept message\nteardown message\nafter scenario")
+	c.Assert(testCase.SystemError.Contents, Equals, "Concept Execution Failure: 'boom'\nstacktrace")
+}
+
+func (s *MySuite) TestToVerifyXmlContentForScreenshotAttachments(c *C) {
+	result := &gauge_messages.ProtoExecutionResult{Failed: true, ErrorMessage: "boom", FailureScreenshotFile: "failure.png", ScreenshotFiles: []string{"custom.png"}}
+	step := &gauge_messages.ProtoItem{ItemType: gauge_messages.ProtoItem_Step, Step: &gauge_messages.ProtoStep{StepExecutionResult: &gauge_messages.ProtoStepExecutionResult{ExecutionResult: result}}}
+	scenario := &gauge_messages.ProtoScenario{
+		ScenarioHeading: "Scenario1",
+		ExecutionStatus: gauge_messages.ExecutionStatus_FAILED,
+		ScenarioItems:   []*gauge_messages.ProtoItem{step},
+		PostHookFailure: &gauge_messages.ProtoHookFailure{ErrorMessage: "hook", FailureScreenshotFile: "hook.png"},
+	}
+	item := &gauge_messages.ProtoItem{Scenario: scenario, ItemType: gauge_messages.ProtoItem_Scenario}
+	spec := &gauge_messages.ProtoSpec{SpecHeading: "HEADING", FileName: "FILENAME", Items: []*gauge_messages.ProtoItem{item}}
+	specResult := &gauge_messages.ProtoSpecResult{ProtoSpec: spec, ScenarioCount: 1, Failed: true, ScenarioFailedCount: 1}
+	suiteResult := &gauge_messages.ProtoSuiteResult{SpecResults: []*gauge_messages.ProtoSpecResult{specResult}}
+	message := &gauge_messages.SuiteExecutionResult{SuiteResult: suiteResult}
+
+	builder := &XmlBuilder{currentId: 0}
+	bytes, err := builder.GetXmlContent(message)
+
+	assertXmlValidation(bytes, c)
+
+	var suites JUnitTestSuites
+	xml.Unmarshal(bytes, &suites)
+
+	c.Assert(err, Equals, nil)
+	c.Assert(suites.Suites[0].TestCases[0].SystemOutput.Contents, Equals, "[[ATTACHMENT|attachments/custom.png]]\n[[ATTACHMENT|attachments/failure.png]]\n[[ATTACHMENT|attachments/hook.png]]")
+	c.Assert(builder.Attachments(), DeepEquals, []Attachment{{File: "custom.png", Path: "attachments/custom.png"}, {File: "failure.png", Path: "attachments/failure.png"}, {File: "hook.png", Path: "attachments/hook.png"}})
+}
+
+func (s *MySuite) TestToVerifyXmlContentForErrorsAndFailures(c *C) {
+	scenType := gauge_messages.ProtoItem_Scenario
+	stepType := gauge_messages.ProtoItem_Step
+	newScenario := func(heading string, result *gauge_messages.ProtoExecutionResult) *gauge_messages.ProtoScenario {
+		step := &gauge_messages.ProtoStep{StepExecutionResult: &gauge_messages.ProtoStepExecutionResult{ExecutionResult: result}}
+		return &gauge_messages.ProtoScenario{ScenarioHeading: heading, ExecutionStatus: gauge_messages.ExecutionStatus_FAILED,
+			ScenarioItems: []*gauge_messages.ProtoItem{{Step: step, ItemType: stepType}}}
+	}
+	assertion := newScenario("Assertion", &gauge_messages.ProtoExecutionResult{Failed: true, ErrorMessage: "expected", ErrorType: gauge_messages.ProtoExecutionResult_ASSERTION})
+	verification := newScenario("Verification", &gauge_messages.ProtoExecutionResult{Failed: true, ErrorMessage: "exception", ErrorType: gauge_messages.ProtoExecutionResult_VERIFICATION})
+	hook := newScenario("Hook", nil)
+	hook.PreHookFailure = &gauge_messages.ProtoHookFailure{ErrorMessage: "hook failed", StackTrace: "stacktrace"}
+
+	items := []*gauge_messages.ProtoItem{{Scenario: assertion, ItemType: scenType}, {Scenario: verification, ItemType: scenType}, {Scenario: hook, ItemType: scenType}}
+	spec := &gauge_messages.ProtoSpec{SpecHeading: "HEADING", FileName: "FILENAME", Items: items}
+	specResult := &gauge_messages.ProtoSpecResult{ProtoSpec: spec, ScenarioCount: 3, Failed: true, ScenarioFailedCount: 3}
+	suiteResult := &gauge_messages.ProtoSuiteResult{SpecResults: []*gauge_messages.ProtoSpecResult{specResult}}
+	message := &gauge_messages.SuiteExecutionResult{SuiteResult: suiteResult}
+
+	builder := &XmlBuilder{currentId: 0}
+	bytes, err := builder.GetXmlContent(message)
+
+	assertXmlValidation(bytes, c)
+
+	var suites JUnitTestSuites
+	xml.Unmarshal(bytes, &suites)
+
+	c.Assert(err, Equals, nil)
+	c.Assert(suites.Suites[0].Failures, Equals, 1)
+	c.Assert(suites.Suites[0].Errors, Equals, 2)
+	c.Assert(suites.Suites[0].TestCases[0].Failure.Message, Equals, "Step Execution Failure: 'expected'")
+	c.Assert(suites.Suites[0].TestCases[0].Error, IsNil)
+	c.Assert(suites.Suites[0].TestCases[1].Error.Message, Equals, "Step Execution Failure: 'exception'")
+	c.Assert(suites.Suites[0].TestCases[1].Failure, IsNil)
+	c.Assert(suites.Suites[0].TestCases[2].Error.Message, Equals, "Hook\nScenario Pre Hook Failure: 'hook failed'")
+	c.Assert(suites.Suites[0].TestCases[2].Error.Contents, Equals, "stacktrace")
+}
+
+func (s *MySuite) TestToVerifyXmlContentForSuiteHookFailures(c *C) {
+	suiteResult := &gauge_messages.ProtoSuiteResult{
+		PreHookFailure:  &gauge_messages.ProtoHookFailure{ErrorMessage: "before failed", StackTrace: "before stacktrace", FailureScreenshotFile: "before.png"},
+		PostHookFailure: &gauge_messages.ProtoHookFailure{ErrorMessage: "after failed", StackTrace: "after stacktrace"},
+	}
+	message := &gauge_messages.SuiteExecutionResult{SuiteResult: suiteResult}
+
+	builder := &XmlBuilder{currentId: 0}
+	bytes, err := builder.GetXmlContent(message)
+
+	assertXmlValidation(bytes, c)
+
+	var suites JUnitTestSuites
+	xml.Unmarshal(bytes, &suites)
+
+	c.Assert(err, Equals, nil)
+	c.Assert(len(suites.Suites), Equals, 1)
+	c.Assert(suites.Suites[0].Name, Equals, suiteHooksName)
+	c.Assert(suites.Suites[0].Tests, Equals, 2)
+	c.Assert(suites.Suites[0].Errors, Equals, 2)
+	c.Assert(len(suites.Suites[0].TestCases), Equals, 2)
+	c.Assert(suites.Suites[0].TestCases[0].Name, Equals, "BeforeSuite")
+	c.Assert(*suites.Suites[0].TestCases[0].Error, Equals, JUnitError{
+		Message:  "Pre Hook Failure: 'before failed'",
+		Type:     "Pre Hook Failure: 'before failed'",
+		Contents: "before stacktrace",
+	})
+	c.Assert(suites.Suites[0].TestCases[0].SystemOutput.Contents, Equals, "[[ATTACHMENT|attachments/before.png]]")
+	c.Assert(suites.Suites[0].TestCases[1].Name, Equals, "AfterSuite")
+	c.Assert(suites.Suites[0].TestCases[1].Error.Message, Equals, "Post Hook Failure: 'after failed'")
+}
+
+func (s *MySuite) TestToVerifyXmlContentWithoutSuiteHookFailures(c *C) {
+	message := &gauge_messages.SuiteExecutionResult{SuiteResult: &gauge_messages.ProtoSuiteResult{}}
+
+	builder := &XmlBuilder{currentId: 0}
+	bytes, err := builder.GetXmlContent(message)
+
+	var suites JUnitTestSuites
+	xml.Unmarshal(bytes, &suites)
+
+	c.Assert(err, Equals, nil)
+	c.Assert(len(suites.Suites), Equals, 0)
+}
+
+func (s *MySuite) TestToVerifyXmlContentForSuiteHookMessagesWithoutFailures(c *C) {
+	message := &gauge_messages.SuiteExecutionResult{SuiteResult: &gauge_messages.ProtoSuiteResult{
+		PreHookMessages: []string{"suite hook msg"}, PostHookMessages: []string{"suite done"},
+	}}
+
+	bytes, err := NewXmlBuilder(0, Config{}).GetXmlContent(message)
+
+	c.Assert(err, Equals, nil)
+	assertXmlValidation(bytes, c)
+	var suites JUnitTestSuites
+	c.Assert(xml.Unmarshal(bytes, &suites), Equals, nil)
+	c.Assert(suites.Tests, Equals, 0)
+	c.Assert(suites.Errors, Equals, 0)
+	c.Assert(len(suites.Suites), Equals, 1)
+	c.Assert(suites.Suites[0].Name, Equals, "Suite Hooks")
+	c.Assert(suites.Suites[0].Tests, Equals, 0)
+	c.Assert(suites.Suites[0].SystemOutput.Contents, Equals, "suite hook msg\nsuite done")
+}
+
+func (s *MySuite) TestToVerifyXmlContentForSpecHookFailures(c *C) {
+	scenario := &gauge_messages.ProtoScenario{ScenarioHeading: "Scenario1", ExecutionStatus: gauge_messages.ExecutionStatus_SKIPPED}
+	item := &gauge_messages.ProtoItem{Scenario: scenario, ItemType: gauge_messages.ProtoItem_Scenario}
+	spec := &gauge_messages.ProtoSpec{
+		SpecHeading:      "HEADING",
+		FileName:         "FILENAME",
+		IsTableDriven:    true,
+		Items:            []*gauge_messages.ProtoItem{item},
+		PreHookFailures:  []*gauge_messages.ProtoHookFailure{{ErrorMessage: "before failed", StackTrace: "before stacktrace", TableRowIndex: 1}},
+		PostHookFailures: []*gauge_messages.ProtoHookFailure{{ErrorMessage: "after failed", StackTrace: "after stacktrace", TableRowIndex: 1}},
+	}
+	specResult := &gauge_messages.ProtoSpecResult{ProtoSpec: spec, ScenarioCount: 1, Failed: true, ScenarioSkippedCount: 1}
+	suiteResult := &gauge_messages.ProtoSuiteResult{SpecResults: []*gauge_messages.ProtoSpecResult{specResult}}
+	message := &gauge_messages.SuiteExecutionResult{SuiteResult: suiteResult}
+
+	builder := &XmlBuilder{currentId: 0}
+	bytes, err := builder.GetXmlContent(message)
+
+	assertXmlValidation(bytes, c)
+
+	var suites JUnitTestSuites
+	xml.Unmarshal(bytes, &suites)
+
+	c.Assert(err, Equals, nil)
+	c.Assert(suites.Suites[0].Tests, Equals, 3)
+	c.Assert(suites.Suites[0].Errors, Equals, 2)
+	c.Assert(suites.Suites[0].Failures, Equals, 0)
+	c.Assert(len(suites.Suites[0].TestCases), Equals, 3)
+	c.Assert(suites.Suites[0].TestCases[0].Classname, Equals, "HEADING")
+	c.Assert(suites.Suites[0].TestCases[0].Name, Equals, "BeforeSpec | SpecRow: 2")
+	c.Assert(*suites.Suites[0].TestCases[0].Error, Equals, JUnitError{
+		Message:  "Pre Hook Failure: 'before failed'",
+		Type:     "Pre Hook Failure: 'before failed'",
+		Contents: "before stacktrace",
+	})
+	c.Assert(suites.Suites[0].TestCases[1].Name, Equals, "Scenario1")
+	c.Assert(suites.Suites[0].TestCases[2].Name, Equals, "AfterSpec | SpecRow: 2")
+	c.Assert(suites.Suites[0].TestCases[2].Error.Contents, Equals, "after stacktrace")
+}
+
+func (s *MySuite) TestToVerifyXmlContentForProperties(c *C) {
+	item := &gauge_messages.ProtoItem{Scenario: &gauge_messages.ProtoScenario{ScenarioHeading: "Scenario1"}, ItemType: gauge_messages.ProtoItem_Scenario}
+	spec := &gauge_messages.ProtoSpec{SpecHeading: "HEADING", FileName: "FILENAME", Tags: []string{"smoke", "login"}, Items: []*gauge_messages.ProtoItem{item}}
+	specResult := &gauge_messages.ProtoSpecResult{ProtoSpec: spec, ScenarioCount: 1}
+	suiteResult := &gauge_messages.ProtoSuiteResult{
+		SpecResults: []*gauge_messages.ProtoSpecResult{specResult},
+		ProjectName: "project",
+		Environment: "ci",
+		Tags:        "smoke & !wip",
+	}
+	message := &gauge_messages.SuiteExecutionResult{SuiteResult: suiteResult}
+
+	builder := NewXmlBuilder(0, Config{Properties: []JUnitProperty{{Name: "env.BUILD_NUMBER", Value: "42"}}})
+	bytes, err := builder.GetXmlContent(message)
+
+	assertXmlValidation(bytes, c)
+
+	var suites JUnitTestSuites
+	xml.Unmarshal(bytes, &suites)
+
+	c.Assert(err, Equals, nil)
+	c.Assert(suites.Suites[0].Properties, DeepEquals, []JUnitProperty{
+		{Name: "spec.tags", Value: "smoke,login"},
+		{Name: "gauge.project", Value: "project"},
+		{Name: "gauge.environment", Value: "ci"},
+		{Name: "gauge.tags", Value: "smoke & !wip"},
+		{Name: "env.BUILD_NUMBER", Value: "42"},
+	})
+}
+
+func (s *MySuite) TestToVerifyXmlContentForTestCaseProperties(c *C) {
+	tableItem := &gauge_messages.ProtoItem{
+		ItemType: gauge_messages.ProtoItem_Table,
+		Table: &gauge_messages.ProtoTable{
+			Headers: &gauge_messages.ProtoTableRow{Cells: []string{"name", "age"}},
+			Rows:    []*gauge_messages.ProtoTableRow{{Cells: []string{"john", "20"}}},
+		},
+	}
+	scenario := &gauge_messages.ProtoScenario{ScenarioHeading: "Scenario", ID: "specs/login.spec:12", Tags: []string{"smoke", "owner:payments"}, RetriesCount: 2}
+	item := &gauge_messages.ProtoItem{TableDrivenScenario: &gauge_messages.ProtoTableDrivenScenario{Scenario: scenario, IsSpecTableDriven: true}, ItemType: gauge_messages.ProtoItem_TableDrivenScenario}
+	spec := &gauge_messages.ProtoSpec{SpecHeading: "HEADING", FileName: "FILENAME", Items: []*gauge_messages.ProtoItem{tableItem, item}}
+	specResult := &gauge_messages.ProtoSpecResult{ProtoSpec: spec, ScenarioCount: 1}
+	suiteResult := &gauge_messages.ProtoSuiteResult{SpecResults: []*gauge_messages.ProtoSpecResult{specResult}}
+	message := &gauge_messages.SuiteExecutionResult{SuiteResult: suiteResult}
+
+	builder := &XmlBuilder{currentId: 0}
+	bytes, err := builder.GetXmlContent(message)
+
+	assertXmlValidation(bytes, c)
+
+	var suites JUnitTestSuites
+	xml.Unmarshal(bytes, &suites)
+
+	c.Assert(err, Equals, nil)
+	c.Assert(suites.Suites[0].TestCases[0].Properties, DeepEquals, []JUnitProperty{
+		{Name: "scenario.tags", Value: "smoke,owner:payments"},
+		{Name: "scenario.id", Value: "specs/login.spec:12"},
+		{Name: "scenario.retries", Value: "2"},
+		{Name: "specRow.name", Value: "john"},
+		{Name: "specRow.age", Value: "20"},
+	})
+}
+
+func (s *MySuite) TestToVerifyXmlContentForFileAndLine(c *C) {
+	projectRoot := filepath.Join("home", "project")
+	scenario := &gauge_messages.ProtoScenario{ScenarioHeading: "Scenario1", Span: &gauge_messages.Span{Start: 42, End: 50}}
+	item := &gauge_messages.ProtoItem{Scenario: scenario, ItemType: gauge_messages.ProtoItem_Scenario}
+	spec := &gauge_messages.ProtoSpec{SpecHeading: "HEADING", FileName: filepath.Join(projectRoot, "specs", "login.spec"), Items: []*gauge_messages.ProtoItem{item}}
+	specResult := &gauge_messages.ProtoSpecResult{ProtoSpec: spec, ScenarioCount: 1}
+	suiteResult := &gauge_messages.ProtoSuiteResult{SpecResults: []*gauge_messages.ProtoSpecResult{specResult}}
+	message := &gauge_messages.SuiteExecutionResult{SuiteResult: suiteResult}
+
+	builder := NewXmlBuilder(0, Config{ProjectRoot: projectRoot})
+	bytes, err := builder.GetXmlContent(message)
+
+	assertXmlValidation(bytes, c)
+
+	var suites JUnitTestSuites
+	xml.Unmarshal(bytes, &suites)
+
+	c.Assert(err, Equals, nil)
+	c.Assert(suites.Suites[0].TestCases[0].File, Equals, "specs/login.spec")
+	c.Assert(suites.Suites[0].TestCases[0].Line, Equals, int64(42))
+}
+
+func (s *MySuite) TestToVerifyXmlContentForTimestamps(c *C) {
+	newSpecResult := func(heading string, executionTime int64, timestamp string) *gauge_messages.ProtoSpecResult {
+		spec := &gauge_messages.ProtoSpec{SpecHeading: heading, FileName: "FILENAME"}
+		return &gauge_messages.ProtoSpecResult{ProtoSpec: spec, ExecutionTime: executionTime, TimestampISO: timestamp}
+	}
+	specResults := []*gauge_messages.ProtoSpecResult{
+		newSpecResult("first", 90000, ""),
+		newSpecResult("second", 1000, ""),
+		newSpecResult("third", 1000, "2024-01-02T11:00:00+05:30"),
+	}
+	suiteResult := &gauge_messages.ProtoSuiteResult{SpecResults: specResults, TimestampISO: "2024-01-02T10:00:00+05:30"}
+	message := &gauge_messages.SuiteExecutionResult{SuiteResult: suiteResult}
+
+	builder := &XmlBuilder{currentId: 0}
+	bytes, err := builder.GetXmlContent(message)
+
+	assertXmlValidation(bytes, c)
+
+	var suites JUnitTestSuites
+	xml.Unmarshal(bytes, &suites)
+
+	c.Assert(err, Equals, nil)
+	c.Assert(suites.Suites[0].Timestamp, Equals, "2024-01-02T10:00:00+05:30")
+	c.Assert(suites.Suites[1].Timestamp, Equals, "2024-01-02T10:01:30+05:30")
+	c.Assert(suites.Suites[2].Timestamp, Equals, "2024-01-02T11:00:00+05:30")
+}
+
+func (s *MySuite) TestToVerifyXmlContentUsesClockWithoutTimestamps(c *C) {
+	spec := &gauge_messages.ProtoSpec{SpecHeading: "HEADING", FileName: "FILENAME"}
+	specResult := &gauge_messages.ProtoSpecResult{ProtoSpec: spec}
+	suiteResult := &gauge_messages.ProtoSuiteResult{SpecResults: []*gauge_messages.ProtoSpecResult{specResult}}
+	message := &gauge_messages.SuiteExecutionResult{SuiteResult: suiteResult}
+	clock := func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	builder := NewXmlBuilder(0, Config{Clock: clock})
+	bytes, err := builder.GetXmlContent(message)
+
+	assertXmlValidation(bytes, c)
+
+	var suites JUnitTestSuites
+	xml.Unmarshal(bytes, &suites)
+
+	c.Assert(err, Equals, nil)
+	c.Assert(suites.Suites[0].Timestamp, Equals, "2024-01-02T03:04:05Z")
+}
+
+func (s *MySuite) TestToVerifyXmlContentForTotals(c *C) {
+	newSpecResult := func(heading string, count, failed, skipped int32) *gauge_messages.ProtoSpecResult {
+		spec := &gauge_messages.ProtoSpec{SpecHeading: heading, FileName: "FILENAME"}
+		return &gauge_messages.ProtoSpecResult{ProtoSpec: spec, ScenarioCount: count, ScenarioFailedCount: failed, ScenarioSkippedCount: skipped}
+	}
+	suiteResult := &gauge_messages.ProtoSuiteResult{
+		SpecResults:    []*gauge_messages.ProtoSpecResult{newSpecResult("first", 3, 1, 0), newSpecResult("second", 2, 0, 1)},
+		PreHookFailure: &gauge_messages.ProtoHookFailure{ErrorMessage: "failed"},
+		ProjectName:    "project",
+		ExecutionTime:  1500,
+		TimestampISO:   "2024-01-02T10:00:00Z",
+	}
+	message := &gauge_messages.SuiteExecutionResult{SuiteResult: suiteResult}
+
+	builder := &XmlBuilder{currentId: 0}
+	bytes, err := builder.GetXmlContent(message)
+
+	assertXmlValidation(bytes, c)
+
+	var suites JUnitTestSuites
+	xml.Unmarshal(bytes, &suites)
+
+	c.Assert(err, Equals, nil)
+	c.Assert(suites.Name, Equals, "project")
+	c.Assert(suites.Tests, Equals, 6)
+	c.Assert(suites.Failures, Equals, 1)
+	c.Assert(suites.Errors, Equals, 1)
+	c.Assert(suites.Skipped, Equals, 1)
+	c.Assert(suites.Time, Equals, "1.500")
+	c.Assert(suites.Timestamp, Equals, "2024-01-02T10:00:00Z")
+}
+
+func (s *MySuite) TestToVerifyXmlContentForRetriedScenarios(c *C) {
+	stepType := gauge_messages.ProtoItem_Step
+	result := &gauge_messages.ProtoExecutionResult{Failed: true, ErrorMessage: "boom", StackTrace: "stacktrace"}
+	step := &gauge_messages.ProtoStep{StepExecutionResult: &gauge_messages.ProtoStepExecutionResult{ExecutionResult: result}}
+	flaky := &gauge_messages.ProtoScenario{ScenarioHeading: "Flaky", ExecutionStatus: gauge_messages.ExecutionStatus_PASSED, RetriesCount: 2}
+	failing := &gauge_messages.ProtoScenario{ScenarioHeading: "Failing", ExecutionStatus: gauge_messages.ExecutionStatus_FAILED, RetriesCount: 1,
+		ScenarioItems: []*gauge_messages.ProtoItem{{Step: step, ItemType: stepType}}}
+	items := []*gauge_messages.ProtoItem{
+		{Scenario: flaky, ItemType: gauge_messages.ProtoItem_Scenario},
+		{Scenario: failing, ItemType: gauge_messages.ProtoItem_Scenario},
+	}
+	spec := &gauge_messages.ProtoSpec{SpecHeading: "HEADING", FileName: "FILENAME", Items: items}
+	specResult := &gauge_messages.ProtoSpecResult{ProtoSpec: spec, ScenarioCount: 2, ScenarioFailedCount: 1, Failed: true}
+	suiteResult := &gauge_messages.ProtoSuiteResult{SpecResults: []*gauge_messages.ProtoSpecResult{specResult}}
+	message := &gauge_messages.SuiteExecutionResult{SuiteResult: suiteResult}
+
+	builder := &XmlBuilder{currentId: 0}
+	bytes, err := builder.GetXmlContent(message)
+
+	assertXmlValidation(bytes, c)
+
+	var suites JUnitTestSuites
+	xml.Unmarshal(bytes, &suites)
+
+	c.Assert(err, Equals, nil)
+	c.Assert(suites.Suites[0].TestCases[0].Flaky, DeepEquals, []JUnitRerun{{Message: "Failed 2 of 3 runs", Type: "Retry"}})
+	c.Assert(suites.Suites[0].TestCases[0].Failure, IsNil)
+	c.Assert(suites.Suites[0].TestCases[1].Reruns, DeepEquals, []JUnitRerun{{Message: "Failed all 2 runs", Type: "Retry"}})
+
+	builder = NewXmlBuilder(0, Config{RetryFormat: RetryFormatNone})
+	bytes, _ = builder.GetXmlContent(message)
+	suites = JUnitTestSuites{}
+	xml.Unmarshal(bytes, &suites)
+
+	c.Assert(suites.Suites[0].TestCases[0].Flaky, IsNil)
+	c.Assert(suites.Suites[0].TestCases[1].Reruns, IsNil)
+}
+
+func (s *MySuite) TestToVerifyXmlContentForTeardownFailures(c *C) {
+	stepType := gauge_messages.ProtoItem_Step
+	result1 := &gauge_messages.ProtoExecutionResult{Failed: true, ErrorMessage: "cleanup failed", StackTrace: "teardown stacktrace"}
+	result2 := &gauge_messages.ProtoExecutionResult{Failed: true, ErrorMessage: "concept cleanup failed", StackTrace: "concept stacktrace"}
+	step1 := &gauge_messages.ProtoStep{StepExecutionResult: &gauge_messages.ProtoStepExecutionResult{ExecutionResult: result1}}
+	step2 := &gauge_messages.ProtoStep{StepExecutionResult: &gauge_messages.ProtoStepExecutionResult{ExecutionResult: result2}}
+	concept := &gauge_messages.ProtoConcept{Steps: []*gauge_messages.ProtoItem{{Step: step2, ItemType: stepType}}}
+	teardown := []*gauge_messages.ProtoItem{{Step: step1, ItemType: stepType}, {Concept: concept, ItemType: gauge_messages.ProtoItem_Concept}}
+
+	scenario := &gauge_messages.ProtoScenario{ScenarioHeading: "Scenario1", TearDownSteps: teardown, ExecutionStatus: gauge_messages.ExecutionStatus_FAILED}
+	spec := &gauge_messages.ProtoSpec{SpecHeading: "HEADING", FileName: "FILENAME", Items: []*gauge_messages.ProtoItem{{Scenario: scenario, ItemType: gauge_messages.ProtoItem_Scenario}}}
+	specResult := &gauge_messages.ProtoSpecResult{ProtoSpec: spec, ScenarioCount: 1, Failed: true, ScenarioFailedCount: 1}
+	suiteResult := &gauge_messages.ProtoSuiteResult{SpecResults: []*gauge_messages.ProtoSpecResult{specResult}}
+	message := &gauge_messages.SuiteExecutionResult{SuiteResult: suiteResult}
+
+	builder := &XmlBuilder{currentId: 0}
+	bytes, err := builder.GetXmlContent(message)
+
+	assertXmlValidation(bytes, c)
+
+	var suites JUnitTestSuites
+	xml.Unmarshal(bytes, &suites)
+
+	c.Assert(err, Equals, nil)
+	failure := `Teardown Step Execution Failure: 'cleanup failed'
+teardown stacktrace
+
+Teardown Concept Execution Failure: 'concept cleanup failed'
+concept stacktrace`
+	c.Assert(suites.Suites[0].TestCases[0].Failure.Message, Equals, "Multiple failures")
+	c.Assert(suites.Suites[0].TestCases[0].Failure.Contents, Equals, failure)
+}
+
+func (s *MySuite) TestToVerifyXmlContentForSkippedScenarios(c *C) {
+	scenType := gauge_messages.ProtoItem_Scenario
+	stepType := gauge_messages.ProtoItem_Step
+	runtimeStep := &gauge_messages.ProtoStep{ActualText: "Check feature flag", StepExecutionResult: &gauge_messages.ProtoStepExecutionResult{
+		ExecutionResult: &gauge_messages.ProtoExecutionResult{SkipScenario: true, ErrorMessage: "flag is off"},
+	}}
+	skippedStep := &gauge_messages.ProtoStep{ActualText: "Log in", StepExecutionResult: &gauge_messages.ProtoStepExecutionResult{Skipped: true, SkippedReason: "scenario skipped"}}
+	validation := &gauge_messages.ProtoScenario{ScenarioHeading: "Validation", ExecutionStatus: gauge_messages.ExecutionStatus_SKIPPED, SkipErrors: []string{"Step implementation not found"}}
+	runtime := &gauge_messages.ProtoScenario{ScenarioHeading: "Runtime", ExecutionStatus: gauge_messages.ExecutionStatus_SKIPPED,
+		ScenarioItems: []*gauge_messages.ProtoItem{{Step: runtimeStep, ItemType: stepType}, {Step: skippedStep, ItemType: stepType}}}
+	unexplained := &gauge_messages.ProtoScenario{ScenarioHeading: "Unexplained", ExecutionStatus: gauge_messages.ExecutionStatus_SKIPPED}
+	items := []*gauge_messages.ProtoItem{{Scenario: validation, ItemType: scenType}, {Scenario: runtime, ItemType: scenType}, {Scenario: unexplained, ItemType: scenType}}
+	spec := &gauge_messages.ProtoSpec{SpecHeading: "HEADING", FileName: "FILENAME", Items: items}
+	specResult := &gauge_messages.ProtoSpecResult{ProtoSpec: spec, ScenarioCount: 3, ScenarioSkippedCount: 3}
+	suiteResult := &gauge_messages.ProtoSuiteResult{SpecResults: []*gauge_messages.ProtoSpecResult{specResult}}
+	message := &gauge_messages.SuiteExecutionResult{SuiteResult: suiteResult}
+
+	builder := &XmlBuilder{currentId: 0}
+	bytes, err := builder.GetXmlContent(message)
+
+	assertXmlValidation(bytes, c)
+
+	var suites JUnitTestSuites
+	xml.Unmarshal(bytes, &suites)
+
+	c.Assert(err, Equals, nil)
+	c.Assert(suites.Suites[0].TestCases[0].SkipMessage.Message, Equals, "[Validation Skip] Step implementation not found")
+	c.Assert(suites.Suites[0].TestCases[1].SkipMessage.Message, Equals, "[Runtime Skip] Check feature flag: flag is off\n[Step Skipped] Log in: scenario skipped")
+	c.Assert(suites.Suites[0].TestCases[2].SkipMessage.Message, Equals, "Scenario was not executed")
+}
+
+func (s *MySuite) TestToVerifyXmlContentSanitizesInvalidCharacters(c *C) {
+	stepType := gauge_messages.ProtoItem_Step
+	result := &gauge_messages.ProtoExecutionResult{Failed: true, ErrorMessage: "\x1b[31mexpected\x1b[0m\x00", StackTrace: "\x1b[2mat step\x1b[0m\x0b\x1b",
+		Message: []string{"\x1b[32mlogged\x1b[0m\x07"}}
+	step := &gauge_messages.ProtoStep{StepExecutionResult: &gauge_messages.ProtoStepExecutionResult{ExecutionResult: result}}
+	scenario := &gauge_messages.ProtoScenario{ScenarioHeading: "Scenario\x01", ExecutionStatus: gauge_messages.ExecutionStatus_FAILED,
+		ScenarioItems: []*gauge_messages.ProtoItem{{Step: step, ItemType: stepType}}}
+	spec := &gauge_messages.ProtoSpec{SpecHeading: "HEADING", FileName: "FILENAME", Items: []*gauge_messages.ProtoItem{{Scenario: scenario, ItemType: gauge_messages.ProtoItem_Scenario}}}
+	specResult := &gauge_messages.ProtoSpecResult{ProtoSpec: spec, ScenarioCount: 1, Failed: true, ScenarioFailedCount: 1}
+	suiteResult := &gauge_messages.ProtoSuiteResult{SpecResults: []*gauge_messages.ProtoSpecResult{specResult},
+		PostHookFailure: &gauge_messages.ProtoHookFailure{ErrorMessage: "\x1b[31mclosed\x1b[0m\x00"}}
+	message := &gauge_messages.SuiteExecutionResult{SuiteResult: suiteResult}
+
+	builder := &XmlBuilder{currentId: 0}
+	bytes, err := builder.GetXmlContent(message)
+
+	assertXmlValidation(bytes, c)
+
+	var suites JUnitTestSuites
+	c.Assert(xml.Unmarshal(bytes, &suites), Equals, nil)
+
+	c.Assert(err, Equals, nil)
+	testCase := suites.Suites[0].TestCases[0]
+	c.Assert(testCase.Name, Equals, "Scenario[U+0001]")
+	c.Assert(testCase.Failure.Message, Equals, "Step Execution Failure: 'expected[U+0000]'")
+	c.Assert(testCase.Failure.Type, Equals, "Step Execution Failure: 'expected[U+0000]'")
+	hookError := suites.Suites[1].TestCases[0].Error
+	c.Assert(hookError.Message, Equals, "Post Hook Failure: 'closed[U+0000]'")
+	c.Assert(hookError.Type, Equals, "Post Hook Failure: 'closed[U+0000]'")
+	c.Assert(testCase.Failure.Contents, Equals, "at step[U+000B][U+001B]")
+	c.Assert(testCase.SystemOutput.Contents, Equals, "logged[U+0007]")
+}
+
+func (s *MySuite) TestToVerifyXmlContentWithCData(c *C) {
+	stepType := gauge_messages.ProtoItem_Step
+	result := &gauge_messages.ProtoExecutionResult{Failed: true, ErrorMessage: "expected", StackTrace: "at <step> & more ]]> trailing",
+		Message: []string{"short"}}
+	step := &gauge_messages.ProtoStep{StepExecutionResult: &gauge_messages.ProtoStepExecutionResult{ExecutionResult: result}}
+	scenario := &gauge_messages.ProtoScenario{ScenarioHeading: "Scenario", ExecutionStatus: gauge_messages.ExecutionStatus_FAILED,
+		ScenarioItems: []*gauge_messages.ProtoItem{{Step: step, ItemType: stepType}}}
+	spec := &gauge_messages.ProtoSpec{SpecHeading: "HEADING", FileName: "FILENAME", Items: []*gauge_messages.ProtoItem{{Scenario: scenario, ItemType: gauge_messages.ProtoItem_Scenario}}}
+	specResult := &gauge_messages.ProtoSpecResult{ProtoSpec: spec, ScenarioCount: 1, Failed: true, ScenarioFailedCount: 1}
+	suiteResult := &gauge_messages.ProtoSuiteResult{SpecResults: []*gauge_messages.ProtoSpecResult{specResult}}
+	message := &gauge_messages.SuiteExecutionResult{SuiteResult: suiteResult}
+
+	builder := NewXmlBuilder(0, Config{CDataThreshold: 10})
+	bytes, err := builder.GetXmlContent(message)
+
+	assertXmlValidation(bytes, c)
+
+	var suites JUnitTestSuites
+	xml.Unmarshal(bytes, &suites)
+
+	c.Assert(err, Equals, nil)
+	c.Assert(strings.Contains(string(bytes), "<![CDATA[at <step> & more ]]"), Equals, true)
+	c.Assert(strings.Contains(string(bytes), "<![CDATA[short"), Equals, false)
+	c.Assert(suites.Suites[0].TestCases[0].Failure.Contents, Equals, "at <step> & more ]]> trailing")
+	c.Assert(suites.Suites[0].TestCases[0].SystemOutput.Contents, Equals, "short")
+}
+
+func (s *MySuite) TestToVerifyXmlContentWithMaxFieldSize(c *C) {
+	stepType := gauge_messages.ProtoItem_Step
+	stackTrace := "at top\n" + strings.Repeat("at middle\n", 100) + "at root cause"
+	result := &gauge_messages.ProtoExecutionResult{Failed: true, ErrorMessage: strings.Repeat("m", 200), StackTrace: stackTrace,
+		Message: []string{strings.Repeat("o", 200)}}
+	step := &gauge_messages.ProtoStep{StepExecutionResult: &gauge_messages.ProtoStepExecutionResult{ExecutionResult: result}}
+	scenario := &gauge_messages.ProtoScenario{ScenarioHeading: "Scenario", ExecutionStatus: gauge_messages.ExecutionStatus_FAILED,
+		ScenarioItems: []*gauge_messages.ProtoItem{{Step: step, ItemType: stepType}}}
+	spec := &gauge_messages.ProtoSpec{SpecHeading: "HEADING", FileName: "FILENAME", Items: []*gauge_messages.ProtoItem{{Scenario: scenario, ItemType: gauge_messages.ProtoItem_Scenario}}}
+	specResult := &gauge_messages.ProtoSpecResult{ProtoSpec: spec, ScenarioCount: 1, Failed: true, ScenarioFailedCount: 1}
+	suiteResult := &gauge_messages.ProtoSuiteResult{SpecResults: []*gauge_messages.ProtoSpecResult{specResult}}
+	message := &gauge_messages.SuiteExecutionResult{SuiteResult: suiteResult}
+
+	builder := NewXmlBuilder(0, Config{MaxFieldSize: 50})
+	bytes, err := builder.GetXmlContent(message)
+
+	assertXmlValidation(bytes, c)
+
+	var suites JUnitTestSuites
+	xml.Unmarshal(bytes, &suites)
+
+	c.Assert(err, Equals, nil)
+	testCase := suites.Suites[0].TestCases[0]
+	c.Assert(testCase.Failure.Message, Equals, "Step Execution Failure: '"+strings.Repeat("m", 25)+" ... [truncated 176 bytes]")
+	c.Assert(testCase.Failure.Type, Equals, testCase.Failure.Message)
+	c.Assert(strings.HasPrefix(testCase.Failure.Contents, "at top\n"), Equals, true)
+	c.Assert(strings.HasSuffix(testCase.Failure.Contents, "\nat root cause"), Equals, true)
+	c.Assert(strings.Contains(testCase.Failure.Contents, "\n... [truncated 970 bytes] ...\n"), Equals, true)
+	c.Assert(testCase.SystemOutput.Contents, Equals, strings.Repeat("o", 50)+" ... [truncated 150 bytes]")
+}
+
+func (s *MySuite) TestToVerifyXmlContentWithMaxFileSize(c *C) {
+	stepType := gauge_messages.ProtoItem_Step
+	var items []*gauge_messages.ProtoItem
+	for i := 0; i < 10; i++ {
+		result := &gauge_messages.ProtoExecutionResult{Failed: true, ErrorMessage: "expected", StackTrace: strings.Repeat("at <step>\n", 1000)}
+		step := &gauge_messages.ProtoStep{StepExecutionResult: &gauge_messages.ProtoStepExecutionResult{ExecutionResult: result}}
+		scenario := &gauge_messages.ProtoScenario{ScenarioHeading: fmt.Sprintf("Scenario %d", i), ExecutionStatus: gauge_messages.ExecutionStatus_FAILED,
+			ScenarioItems: []*gauge_messages.ProtoItem{{Step: step, ItemType: stepType}}}
+		items = append(items, &gauge_messages.ProtoItem{Scenario: scenario, ItemType: gauge_messages.ProtoItem_Scenario})
+	}
+	spec := &gauge_messages.ProtoSpec{SpecHeading: "HEADING", FileName: "FILENAME", Items: items}
+	specResult := &gauge_messages.ProtoSpecResult{ProtoSpec: spec, ScenarioCount: 10, Failed: true, ScenarioFailedCount: 10}
+	suiteResult := &gauge_messages.ProtoSuiteResult{SpecResults: []*gauge_messages.ProtoSpecResult{specResult}}
+	message := &gauge_messages.SuiteExecutionResult{SuiteResult: suiteResult}
+
+	builder := NewXmlBuilder(0, Config{MaxFileSize: 20000})
+	bytes, err := builder.GetXmlContent(message)
+
+	assertXmlValidation(bytes, c)
+
+	var suites JUnitTestSuites
+	xml.Unmarshal(bytes, &suites)
+
+	c.Assert(err, Equals, nil)
+	c.Assert(len(bytes) <= 20000, Equals, true, Commentf("report is %d bytes", len(bytes)))
+	c.Assert(len(bytes) > 15000, Equals, true, Commentf("report is %d bytes", len(bytes)))
+	for _, testCase := range suites.Suites[0].TestCases {
+		c.Assert(testCase.Failure.Message, Equals, "Step Execution Failure: 'expected'")
+		c.Assert(strings.Contains(testCase.Failure.Contents, "... [truncated "), Equals, true)
+	}
+}
+
+func (s *MySuite) TestToVerifyXmlContentWithMaxFileSizeOfSpecialCharacters(c *C) {
+	for _, threshold := range []int{0, 1} {
+		bytes, err := NewXmlBuilder(0, Config{MaxFileSize: oversizedFileSize, CDataThreshold: threshold}).Encode(newOversizedReport())
+
+		c.Assert(err, Equals, nil)
+		assertXmlValidation(bytes, c)
+		assertWithinOversizedFileSize(c, bytes)
+		var suites JUnitTestSuites
+		c.Assert(xml.Unmarshal(bytes, &suites), Equals, nil)
+		for _, testCase := range suites.Suites[0].TestCases {
+			c.Assert(strings.HasPrefix(testCase.Failure.Contents, "at <step> & ]]>\n"), Equals, true)
+			c.Assert(strings.Contains(testCase.Failure.Contents, "... [truncated "), Equals, true)
+		}
+	}
+}
+
+func (s *MySuite) TestToVerifyXmlContentForDeterministicReports(c *C) {
+	message := &gauge_messages.SuiteExecutionResult{SuiteResult: &gauge_messages.ProtoSuiteResult{
+		TimestampISO: "2021-01-01T10:00:00Z", ExecutionTime: 30,
+		SpecResults: []*gauge_messages.ProtoSpecResult{
+			newDeterministicSpecResult("specs/b.spec", 10, newDeterministicScenario("B2", 8, 4), newDeterministicScenario("B1", 3, 6)),
+			newDeterministicSpecResult("specs/a.spec", 20, newDeterministicScenario("A1", 2, 20)),
+		},
+	}}
+
+	bytes, err := NewXmlBuilder(0, Config{Deterministic: true}).GetXmlContent(message)
+
+	c.Assert(err, Equals, nil)
+	assertXmlValidation(bytes, c)
+}
+
 func assertXmlValidation(xml []byte, c *C) {
 	doc, err := libxml2.Parse(xml)
 	c.Assert(err, Equals, nil)
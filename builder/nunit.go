@@ -0,0 +1,500 @@
+/*----------------------------------------------------------------
+ *  Copyright (c) ThoughtWorks, Inc.
+ *  Licensed under the Apache License, Version 2.0
+ *  See LICENSE in the project root for license information.
+ *----------------------------------------------------------------*/
+
+package builder
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/getgauge/xml-report/model"
+)
+
+// NUnitFormat is the name of the NUnit 3 XML format.
+const NUnitFormat = "nunit"
+
+const (
+	nunitTimeFormat      = "2006-01-02 15:04:05Z"
+	nunitChildFailureMsg = "One or more child tests had errors"
+	nunitFirstId         = 1000
+)
+
+// Values of the NUnit result, label, site and runstate attributes.
+const (
+	nunitPassed      = "Passed"
+	nunitFailed      = "Failed"
+	nunitSkipped     = "Skipped"
+	nunitError       = "Error"
+	nunitIgnored     = "Ignored"
+	nunitInvalid     = "Invalid"
+	nunitSetUp       = "SetUp"
+	nunitTearDown    = "TearDown"
+	nunitChild       = "Child"
+	nunitRunnable    = "Runnable"
+	nunitNotRunnable = "NotRunnable"
+)
+
+// Values of the NUnit test-suite type attribute.
+const (
+	nunitAssembly            = "Assembly"
+	nunitTestFixture         = "TestFixture"
+	nunitParameterizedMethod = "ParameterizedMethod"
+)
+
+// NUnitTestRun is the root element of an NUnit 3 result file. It holds a
+// single Assembly for the project.
+type NUnitTestRun struct {
+	XMLName       xml.Name `xml:"test-run"`
+	Id            string   `xml:"id,attr"`
+	Name          string   `xml:"name,attr"`
+	FullName      string   `xml:"fullname,attr"`
+	TestCaseCount int      `xml:"testcasecount,attr"`
+	Result        string   `xml:"result,attr"`
+	NUnitTotals
+	StartTime string           `xml:"start-time,attr"`
+	EndTime   string           `xml:"end-time,attr"`
+	Duration  string           `xml:"duration,attr"`
+	Suites    []NUnitTestSuite `xml:"test-suite"`
+}
+
+// NUnitTotals are the counts of the test cases of a test run or test suite.
+type NUnitTotals struct {
+	Total        int `xml:"total,attr"`
+	Passed       int `xml:"passed,attr"`
+	Failed       int `xml:"failed,attr"`
+	Warnings     int `xml:"warnings,attr"`
+	Inconclusive int `xml:"inconclusive,attr"`
+	Skipped      int `xml:"skipped,attr"`
+	Asserts      int `xml:"asserts,attr"`
+}
+
+// NUnitTestSuite is the Assembly of the project, the TestFixture of a spec or
+// the ParameterizedMethod grouping the rows of a table driven scenario.
+type NUnitTestSuite struct {
+	XMLName       xml.Name `xml:"test-suite"`
+	Type          string   `xml:"type,attr"`
+	Id            string   `xml:"id,attr"`
+	Name          string   `xml:"name,attr"`
+	FullName      string   `xml:"fullname,attr"`
+	ClassName     string   `xml:"classname,attr,omitempty"`
+	RunState      string   `xml:"runstate,attr"`
+	TestCaseCount int      `xml:"testcasecount,attr"`
+	Result        string   `xml:"result,attr"`
+	Label         string   `xml:"label,attr,omitempty"`
+	Site          string   `xml:"site,attr,omitempty"`
+	StartTime     string   `xml:"start-time,attr"`
+	EndTime       string   `xml:"end-time,attr"`
+	Duration      string   `xml:"duration,attr"`
+	NUnitTotals
+	Properties  []NUnitProperty   `xml:"properties>property,omitempty"`
+	Failure     *NUnitFailure     `xml:"failure,omitempty"`
+	Output      *CDataText        `xml:"output,omitempty"`
+	Attachments []NUnitAttachment `xml:"attachments>attachment,omitempty"`
+	TestCases   []NUnitTestCase   `xml:"test-case"`
+	Suites      []NUnitTestSuite  `xml:"test-suite"`
+}
+
+// NUnitTestCase is the result of a scenario, or of one data table row of a
+// table driven scenario.
+type NUnitTestCase struct {
+	XMLName     xml.Name          `xml:"test-case"`
+	Id          string            `xml:"id,attr"`
+	Name        string            `xml:"name,attr"`
+	FullName    string            `xml:"fullname,attr"`
+	MethodName  string            `xml:"methodname,attr"`
+	ClassName   string            `xml:"classname,attr"`
+	RunState    string            `xml:"runstate,attr"`
+	Result      string            `xml:"result,attr"`
+	Label       string            `xml:"label,attr,omitempty"`
+	StartTime   string            `xml:"start-time,attr"`
+	EndTime     string            `xml:"end-time,attr"`
+	Duration    string            `xml:"duration,attr"`
+	Asserts     int               `xml:"asserts,attr"`
+	Properties  []NUnitProperty   `xml:"properties>property,omitempty"`
+	Failure     *NUnitFailure     `xml:"failure,omitempty"`
+	Reason      *NUnitReason      `xml:"reason,omitempty"`
+	Output      *CDataText        `xml:"output,omitempty"`
+	Attachments []NUnitAttachment `xml:"attachments>attachment,omitempty"`
+}
+
+// NUnitProperty is a name/value pair, written like JUnitProperty.
+type NUnitProperty = JUnitProperty
+
+// NUnitFailure holds the message and stack trace of a failed test case, or
+// of an error of a test suite itself, e.g. a failed hook.
+type NUnitFailure struct {
+	Message    CDataText  `xml:"message"`
+	StackTrace *CDataText `xml:"stack-trace,omitempty"`
+}
+
+// NUnitReason holds the reason why a test case was skipped.
+type NUnitReason struct {
+	Message CDataText `xml:"message"`
+}
+
+// NUnitAttachment is a screenshot, relative to the report directory.
+type NUnitAttachment struct {
+	FilePath string `xml:"filePath"`
+}
+
+// NUnitBuilder writes the NUnit 3 XML report of a suite. Specs are reported
+// as TestFixtures and scenarios as TestCases. The rows of a table driven
+// scenario are grouped in a ParameterizedMethod.
+type NUnitBuilder struct {
+	config      Config
+	run         NUnitTestRun
+	lastId      int
+	attachments []Attachment
+}
+
+func NewNUnitBuilder(config Config) *NUnitBuilder {
+	return &NUnitBuilder{config: config}
+}
+
+// Name returns NUnitFormat.
+func (n *NUnitBuilder) Name() string {
+	return NUnitFormat
+}
+
+// FileExtension returns ".nunit.xml", so that the report does not replace
+// the JUnit result.xml.
+func (n *NUnitBuilder) FileExtension() string {
+	return ".nunit.xml"
+}
+
+// Attachments returns the screenshot files referenced by the last generated report.
+func (n *NUnitBuilder) Attachments() []Attachment {
+	return n.attachments
+}
+
+// Encode returns the NUnit 3 XML report of the suite.
+func (n *NUnitBuilder) Encode(report *model.Report) ([]byte, error) {
+	n.lastId = nunitFirstId
+	n.attachments = nil
+	specs := report.Specs
+	if n.config.Deterministic {
+		specs = sortSpecs(specs)
+	}
+	assembly := n.getAssembly(report)
+	for _, spec := range specs {
+		assembly.Suites = append(assembly.Suites, n.getFixture(spec))
+	}
+	setNUnitTotals(&assembly)
+	n.run = NUnitTestRun{
+		Id:            "0",
+		Name:          assembly.Name,
+		FullName:      assembly.FullName,
+		TestCaseCount: assembly.TestCaseCount,
+		Result:        assembly.Result,
+		NUnitTotals:   assembly.NUnitTotals,
+		StartTime:     assembly.StartTime,
+		EndTime:       assembly.EndTime,
+		Duration:      assembly.Duration,
+		Suites:        []NUnitTestSuite{assembly},
+	}
+	truncateAll(n.getTruncatableFields(), n.config.MaxFieldSize)
+	return marshalWithin(n.run, n.config.MaxFileSize, n.getTruncatableFields)
+}
+
+// getAssembly returns the Assembly of the project, which reports the suite
+// hooks and their output.
+func (n *NUnitBuilder) getAssembly(report *model.Report) NUnitTestSuite {
+	name := sanitizeText(report.ProjectName)
+	assembly := NUnitTestSuite{
+		Type:       nunitAssembly,
+		Id:         n.nextId(),
+		Name:       name,
+		FullName:   name,
+		RunState:   nunitRunnable,
+		Properties: getReportProperties(report, n.config),
+		Output:     newCDataText(strings.Join(report.Output, "\n")),
+	}
+	sanitizeProperties(assembly.Properties)
+	assembly.StartTime, assembly.EndTime, assembly.Duration = n.getTimes(report.StartTime, report.Duration)
+	n.addHookErrors(&assembly, report.BeforeHook, report.AfterHook)
+	return assembly
+}
+
+// getFixture returns the TestFixture of the spec. Parse errors make the
+// fixture not runnable, while spec hook failures and validation errors
+// outside any scenario are reported as errors of the fixture.
+func (n *NUnitBuilder) getFixture(spec *model.Spec) NUnitTestSuite {
+	classname := sanitizeText(getSpecClassname(spec, n.config.ClassnameStrategy))
+	fixture := NUnitTestSuite{
+		Type:       nunitTestFixture,
+		Id:         n.nextId(),
+		Name:       sanitizeText(spec.Name),
+		FullName:   classname,
+		ClassName:  classname,
+		RunState:   nunitRunnable,
+		Properties: getCategories(spec.Tags),
+		Output:     newCDataText(strings.Join(spec.Output, "\n")),
+	}
+	fixture.StartTime, fixture.EndTime, fixture.Duration = n.getTimes(spec.StartTime, spec.Duration)
+	if spec.HasParseErrors() {
+		fixture.RunState = nunitNotRunnable
+		fixture.Result, fixture.Label = nunitFailed, nunitInvalid
+		fixture.Failure = newNUnitFailure(joinErrors(spec.Errors), "")
+		return fixture
+	}
+	n.addHookErrors(&fixture, spec.BeforeHooks...)
+	if len(spec.ValidationErrors) > 0 {
+		addSuiteError(&fixture, nunitSetUp, joinErrors(spec.ValidationErrors), "")
+	}
+	methods := map[string]int{}
+	var methodStarts []time.Time
+	var methodDurations []time.Duration
+	start := spec.StartTime
+	for _, scenario := range spec.Scenarios {
+		testCase := n.getTestCase(classname, scenario, start)
+		if scenario.SpecRow == nil && scenario.ScenarioRow == nil {
+			fixture.TestCases = append(fixture.TestCases, testCase)
+		} else {
+			i, ok := methods[scenario.Heading]
+			if !ok {
+				i = len(fixture.Suites)
+				methods[scenario.Heading] = i
+				fixture.Suites = append(fixture.Suites, n.getParameterizedMethod(classname, scenario.Heading))
+				methodStarts = append(methodStarts, start)
+				methodDurations = append(methodDurations, 0)
+			}
+			fixture.Suites[i].TestCases = append(fixture.Suites[i].TestCases, testCase)
+			methodDurations[i] += scenario.Duration
+		}
+		start = start.Add(scenario.Duration)
+	}
+	for i := range fixture.Suites {
+		method := &fixture.Suites[i]
+		method.StartTime, method.EndTime, method.Duration = n.getTimes(methodStarts[i], methodDurations[i])
+		setNUnitTotals(method)
+	}
+	n.addHookErrors(&fixture, spec.AfterHooks...)
+	setNUnitTotals(&fixture)
+	return fixture
+}
+
+// getParameterizedMethod returns the ParameterizedMethod grouping the rows of
+// a table driven scenario.
+func (n *NUnitBuilder) getParameterizedMethod(classname, heading string) NUnitTestSuite {
+	heading = sanitizeText(heading)
+	return NUnitTestSuite{
+		Type:      nunitParameterizedMethod,
+		Id:        n.nextId(),
+		Name:      heading,
+		FullName:  classname + "." + heading,
+		ClassName: classname,
+		RunState:  nunitRunnable,
+	}
+}
+
+// getTestCase returns the TestCase of the scenario, which started at start.
+func (n *NUnitBuilder) getTestCase(classname string, scenario *model.Scenario, start time.Time) NUnitTestCase {
+	name := sanitizeText(scenario.Name)
+	properties := append(getCategories(scenario.Tags), getScenarioProperties(scenario)...)
+	sanitizeProperties(properties)
+	testCase := NUnitTestCase{
+		Id:         n.nextId(),
+		Name:       name,
+		FullName:   classname + "." + name,
+		MethodName: sanitizeText(scenario.Heading),
+		ClassName:  classname,
+		RunState:   nunitRunnable,
+		Result:     nunitPassed,
+		Properties: properties,
+	}
+	testCase.StartTime, testCase.EndTime, testCase.Duration = n.getTimes(start, scenario.Duration)
+	if scenario.Status == model.Failed {
+		testCase.Result = nunitFailed
+		if scenario.Errored() {
+			testCase.Label = nunitError
+		}
+		testCase.Failure = newNUnitFailure(scenario.Failures.Summary())
+	} else if len(scenario.Errors) > 0 {
+		testCase.Result, testCase.Label = nunitFailed, nunitError
+		testCase.Failure = newNUnitFailure(joinErrors(scenario.Errors), "")
+	} else if scenario.Status == model.Skipped {
+		testCase.Result, testCase.Label = nunitSkipped, nunitIgnored
+		testCase.Reason = &NUnitReason{Message: CDataText{Text: sanitizeText(strings.Join(scenario.SkipReasons, "\n"))}}
+	}
+	var messages []string
+	for _, o := range scenario.Output {
+		if o.Screenshot != "" {
+			testCase.Attachments = append(testCase.Attachments, n.getAttachments(o.Screenshot)...)
+		} else {
+			messages = append(messages, o.Message)
+		}
+	}
+	testCase.Output = newCDataText(strings.Join(messages, "\n"))
+	return testCase
+}
+
+// addHookErrors reports failed hooks as errors of the suite, the way NUnit
+// reports failed OneTimeSetUp and OneTimeTearDown methods.
+func (n *NUnitBuilder) addHookErrors(suite *NUnitTestSuite, hooks ...*model.Hook) {
+	for _, hook := range hooks {
+		if hook == nil {
+			continue
+		}
+		site := nunitTearDown
+		if strings.HasPrefix(hook.Name, "Before") {
+			site = nunitSetUp
+		}
+		message := hook.Failure.Message
+		if hook.TableRow >= 0 {
+			message = fmt.Sprintf("%s: %s", hook.Name, message)
+		}
+		addSuiteError(suite, site, message, hook.Failure.StackTrace)
+		suite.Attachments = append(suite.Attachments, n.getAttachments(hook.Screenshot)...)
+	}
+}
+
+// addSuiteError fails the suite with an error of its own. Further errors are
+// appended to the failure, whose site is the one of the first error.
+func addSuiteError(suite *NUnitTestSuite, site, message, stackTrace string) {
+	suite.Result, suite.Label = nunitFailed, nunitError
+	if suite.Failure == nil {
+		suite.Site = site
+		suite.Failure = newNUnitFailure(message, stackTrace)
+		return
+	}
+	suite.Failure.Message.Text += "\n" + sanitizeText(message)
+	if stackTrace == "" {
+		return
+	}
+	if suite.Failure.StackTrace == nil {
+		suite.Failure.StackTrace = &CDataText{}
+	} else {
+		suite.Failure.StackTrace.Text += "\n\n"
+	}
+	suite.Failure.StackTrace.Text += sanitizeText(stackTrace)
+}
+
+// setNUnitTotals counts the test cases of the suite and its child suites,
+// which are expected to be counted already, and sets the result of a suite
+// without an error of its own: it fails if any child failed, and is skipped
+// if all its test cases were skipped.
+func setNUnitTotals(suite *NUnitTestSuite) {
+	totals := NUnitTotals{}
+	childFailed := false
+	for _, testCase := range suite.TestCases {
+		totals.Total++
+		switch testCase.Result {
+		case nunitPassed:
+			totals.Passed++
+		case nunitFailed:
+			totals.Failed++
+		case nunitSkipped:
+			totals.Skipped++
+		}
+	}
+	for _, child := range suite.Suites {
+		totals.Total += child.Total
+		totals.Passed += child.Passed
+		totals.Failed += child.Failed
+		totals.Skipped += child.Skipped
+		childFailed = childFailed || child.Result == nunitFailed
+	}
+	suite.NUnitTotals = totals
+	suite.TestCaseCount = totals.Total
+	if suite.Failure != nil {
+		return
+	}
+	switch {
+	case totals.Failed > 0 || childFailed:
+		suite.Result, suite.Site = nunitFailed, nunitChild
+		suite.Failure = newNUnitFailure(nunitChildFailureMsg, "")
+	case totals.Skipped > 0 && totals.Passed == 0:
+		suite.Result, suite.Label = nunitSkipped, nunitIgnored
+	default:
+		suite.Result = nunitPassed
+	}
+}
+
+// getAttachments records the given screenshot files and returns them as
+// NUnit attachments. NUnit consumers expect the full path of the copied file
+// in ReportDir; deterministic reports keep it relative to the report
+// directory, which changes on every run.
+func (n *NUnitBuilder) getAttachments(files ...string) []NUnitAttachment {
+	var attachments []NUnitAttachment
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+		n.attachments = append(n.attachments, Attachment{File: file, Path: AttachmentPath(file)})
+		filePath := AttachmentPath(file)
+		if !n.config.Deterministic && n.config.ReportDir != "" {
+			filePath = filepath.Join(n.config.ReportDir, filepath.FromSlash(filePath))
+		}
+		attachments = append(attachments, NUnitAttachment{FilePath: filePath})
+	}
+	return attachments
+}
+
+// getTimes returns the start time, end time and duration attributes. They
+// are fixed in deterministic mode.
+func (n *NUnitBuilder) getTimes(start time.Time, duration time.Duration) (string, string, string) {
+	if n.config.Deterministic {
+		start, duration = time.Unix(0, 0), 0
+	}
+	return start.UTC().Format(nunitTimeFormat), start.Add(duration).UTC().Format(nunitTimeFormat), formatTime(duration)
+}
+
+func (n *NUnitBuilder) nextId() string {
+	n.lastId++
+	return fmt.Sprintf("0-%d", n.lastId)
+}
+
+// getTruncatableFields returns the fields of the report which may be truncated.
+func (n *NUnitBuilder) getTruncatableFields() []truncatableField {
+	var fields []truncatableField
+	addText := func(text *CDataText, keepTail bool) {
+		if text != nil {
+			fields = append(fields, truncatableField{value: &text.Text, keepTail: keepTail})
+		}
+	}
+	addFailure := func(failure *NUnitFailure) {
+		if failure != nil {
+			addText(&failure.Message, false)
+			addText(failure.StackTrace, true)
+		}
+	}
+	var addSuite func(suite *NUnitTestSuite)
+	addSuite = func(suite *NUnitTestSuite) {
+		addFailure(suite.Failure)
+		addText(suite.Output, false)
+		for i := range suite.TestCases {
+			testCase := &suite.TestCases[i]
+			addFailure(testCase.Failure)
+			if testCase.Reason != nil {
+				addText(&testCase.Reason.Message, false)
+			}
+			addText(testCase.Output, false)
+		}
+		for i := range suite.Suites {
+			addSuite(&suite.Suites[i])
+		}
+	}
+	for i := range n.run.Suites {
+		addSuite(&n.run.Suites[i])
+	}
+	return fields
+}
+
+// getCategories reports tags as NUnit Category properties.
+func getCategories(tags []string) []NUnitProperty {
+	var properties []NUnitProperty
+	for _, tag := range tags {
+		properties = appendProperty(properties, "Category", sanitizeText(tag))
+	}
+	return properties
+}
+
+func newNUnitFailure(message, stackTrace string) *NUnitFailure {
+	return &NUnitFailure{Message: CDataText{Text: sanitizeText(message)}, StackTrace: newCDataText(stackTrace)}
+}
@@ -0,0 +1,198 @@
+/*----------------------------------------------------------------
+ *  Copyright (c) ThoughtWorks, Inc.
+ *  Licensed under the Apache License, Version 2.0
+ *  See LICENSE in the project root for license information.
+ *----------------------------------------------------------------*/
+
+package builder
+
+import (
+	"encoding/xml"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/getgauge/xml-report/model"
+	. "gopkg.in/check.v1"
+)
+
+func encodeNUnit(c *C, config Config, report *model.Report) NUnitTestRun {
+	bytes, err := NewNUnitBuilder(config).Encode(report)
+	c.Assert(err, Equals, nil)
+
+	var run NUnitTestRun
+	c.Assert(xml.Unmarshal(bytes, &run), Equals, nil)
+	return run
+}
+
+func (s *MySuite) TestNewEncoderForNUnit(c *C) {
+	encoder, err := NewEncoder(NUnitFormat, Config{})
+
+	c.Assert(err, Equals, nil)
+	c.Assert(encoder.Name(), Equals, "nunit")
+	c.Assert(encoder.FileExtension(), Equals, ".nunit.xml")
+}
+
+func (s *MySuite) TestNUnitReportsScenarioResults(c *C) {
+	run := encodeNUnit(c, Config{}, newCheckoutReport())
+
+	c.Assert(run.Result, Equals, "Failed")
+	c.Assert(run.NUnitTotals, Equals, NUnitTotals{Total: 4, Passed: 1, Failed: 2, Skipped: 1})
+	c.Assert(run.StartTime, Equals, "2021-03-04 05:06:07Z")
+	c.Assert(run.EndTime, Equals, "2021-03-04 05:06:10Z")
+	assembly := run.Suites[0]
+	c.Assert(assembly.Type, Equals, "Assembly")
+	c.Assert(assembly.Name, Equals, "shop")
+	c.Assert(assembly.Properties, DeepEquals, []NUnitProperty{{Name: "gauge.project", Value: "shop"}, {Name: "gauge.environment", Value: "ci"}})
+	fixture := assembly.Suites[0]
+	c.Assert(fixture.Type, Equals, "TestFixture")
+	c.Assert(fixture.FullName, Equals, "Checkout")
+	c.Assert(fixture.Result, Equals, "Failed")
+	c.Assert(fixture.Site, Equals, "Child")
+	c.Assert(fixture.Properties, DeepEquals, []NUnitProperty{{Name: "Category", Value: "checkout"}})
+	c.Assert(fixture.TestCaseCount, Equals, 4)
+
+	passed, failed, skipped, errored := fixture.TestCases[0], fixture.TestCases[1], fixture.TestCases[2], fixture.TestCases[3]
+	c.Assert(passed.Result, Equals, "Passed")
+	c.Assert(passed.FullName, Equals, "Checkout.Pay")
+	c.Assert(passed.Duration, Equals, "1.500")
+	c.Assert(passed.Properties, DeepEquals, []NUnitProperty{{Name: "Category", Value: "smoke"}, {Name: "Category", Value: "checkout"}})
+	c.Assert(failed.Result, Equals, "Failed")
+	c.Assert(failed.Label, Equals, "")
+	c.Assert(failed.StartTime, Equals, "2021-03-04 05:06:08Z")
+	c.Assert(failed.Failure.Message.Text, Equals, "boom")
+	c.Assert(failed.Failure.StackTrace.Text, Equals, "trace")
+	c.Assert(skipped.Result, Equals, "Skipped")
+	c.Assert(skipped.Label, Equals, "Ignored")
+	c.Assert(skipped.Reason.Message.Text, Equals, "not ready")
+	c.Assert(errored.Label, Equals, "Error")
+	c.Assert(errored.Failure.StackTrace, IsNil)
+}
+
+func (s *MySuite) TestNUnitGroupsTableDrivenScenarios(c *C) {
+	row := func(index int, value string) *model.TableRow {
+		return &model.TableRow{Index: index, Headers: []string{"card"}, Cells: []string{value}}
+	}
+	report := &model.Report{Specs: []*model.Spec{{
+		Name: "Payment",
+		Scenarios: []*model.Scenario{
+			{Name: "Pay | SpecRow: 1", Heading: "Pay", SpecRow: row(0, "visa"), Status: model.Passed, Duration: time.Second},
+			{Name: "Log out", Heading: "Log out", Status: model.Passed},
+			{Name: "Pay | SpecRow: 2", Heading: "Pay", SpecRow: row(1, "amex"), Status: model.Failed, Duration: 2 * time.Second},
+		},
+	}}}
+
+	run := encodeNUnit(c, Config{}, report)
+
+	fixture := run.Suites[0].Suites[0]
+	c.Assert(fixture.TestCaseCount, Equals, 3)
+	c.Assert(len(fixture.TestCases), Equals, 1)
+	c.Assert(fixture.TestCases[0].Name, Equals, "Log out")
+	method := fixture.Suites[0]
+	c.Assert(method.Type, Equals, "ParameterizedMethod")
+	c.Assert(method.FullName, Equals, "Payment.Pay")
+	c.Assert(method.Result, Equals, "Failed")
+	c.Assert(method.Duration, Equals, "3.000")
+	c.Assert(method.NUnitTotals, Equals, NUnitTotals{Total: 2, Passed: 1, Failed: 1})
+	c.Assert(method.TestCases[1].Name, Equals, "Pay | SpecRow: 2")
+	c.Assert(method.TestCases[1].MethodName, Equals, "Pay")
+	c.Assert(method.TestCases[1].Properties, DeepEquals, []NUnitProperty{{Name: "specRow.card", Value: "amex"}})
+}
+
+func (s *MySuite) TestNUnitReportsHookFailuresAsSuiteErrors(c *C) {
+	report := &model.Report{
+		BeforeHook: &model.Hook{Name: "BeforeSuite", Failure: model.Failure{Message: "Pre Hook Failure: 'db down'", StackTrace: "suite trace"}, Screenshot: "suite.png", TableRow: -1},
+		Specs: []*model.Spec{{
+			Name:        "Payment",
+			BeforeHooks: []*model.Hook{{Name: "BeforeSpec", Failure: model.Failure{Message: "before"}, TableRow: -1}},
+			AfterHooks:  []*model.Hook{{Name: "AfterSpec | SpecRow: 2", Failure: model.Failure{Message: "after", StackTrace: "after trace"}, TableRow: 1}},
+		}},
+	}
+	builder := NewNUnitBuilder(Config{})
+	bytes, err := builder.Encode(report)
+	c.Assert(err, Equals, nil)
+
+	var run NUnitTestRun
+	c.Assert(xml.Unmarshal(bytes, &run), Equals, nil)
+
+	assembly := run.Suites[0]
+	c.Assert(assembly.Result, Equals, "Failed")
+	c.Assert(assembly.Label, Equals, "Error")
+	c.Assert(assembly.Site, Equals, "SetUp")
+	c.Assert(assembly.Failure.Message.Text, Equals, "Pre Hook Failure: 'db down'")
+	c.Assert(assembly.Attachments, DeepEquals, []NUnitAttachment{{FilePath: "attachments/suite.png"}})
+	fixture := assembly.Suites[0]
+	c.Assert(fixture.Site, Equals, "SetUp")
+	c.Assert(fixture.Failure.Message.Text, Equals, "before\nAfterSpec | SpecRow: 2: after")
+	c.Assert(fixture.Failure.StackTrace.Text, Equals, "after trace")
+	c.Assert(builder.Attachments(), DeepEquals, []Attachment{{File: "suite.png", Path: "attachments/suite.png"}})
+}
+
+func (s *MySuite) TestNUnitReportsParseErrors(c *C) {
+	report := &model.Report{Specs: []*model.Spec{{
+		Name:   "Broken",
+		Errors: []model.Error{{Type: model.ParseError, File: "specs/broken.spec", Line: 3, Message: "missing heading"}},
+	}}}
+
+	run := encodeNUnit(c, Config{}, report)
+
+	fixture := run.Suites[0].Suites[0]
+	c.Assert(fixture.RunState, Equals, "NotRunnable")
+	c.Assert(fixture.Result, Equals, "Failed")
+	c.Assert(fixture.Label, Equals, "Invalid")
+	c.Assert(fixture.Failure.Message.Text, Equals, "[Parse Error] specs/broken.spec:3: missing heading")
+	c.Assert(run.Result, Equals, "Failed")
+	c.Assert(run.Suites[0].Site, Equals, "Child")
+}
+
+func (s *MySuite) TestNUnitReportsOutputAndScreenshots(c *C) {
+	reportDir := filepath.FromSlash("/reports/xml-report/run")
+	builder := NewNUnitBuilder(Config{ReportDir: reportDir})
+
+	bytes, err := builder.Encode(newOutputReport())
+
+	c.Assert(err, Equals, nil)
+	c.Assert(strings.Contains(string(bytes), "<output><![CDATA[paid ]]]]><![CDATA[> done\nlogged out]]></output>"), Equals, true)
+	var run NUnitTestRun
+	c.Assert(xml.Unmarshal(bytes, &run), Equals, nil)
+	testCase := run.Suites[0].Suites[0].TestCases[0]
+	c.Assert(testCase.Output.Text, Equals, "paid ]]> done\nlogged out")
+	c.Assert(testCase.Attachments, DeepEquals, []NUnitAttachment{{FilePath: filepath.Join(reportDir, "attachments", "paid.png")}})
+	c.Assert(builder.Attachments(), DeepEquals, []Attachment{{File: "paid.png", Path: "attachments/paid.png"}})
+}
+
+func (s *MySuite) TestNUnitTruncatesFields(c *C) {
+	run := encodeNUnit(c, Config{MaxFieldSize: truncatedFieldSize}, newTruncatedReport())
+
+	testCase := run.Suites[0].Suites[0].TestCases[0]
+	c.Assert(testCase.Name, Equals, "Pay")
+	c.Assert(testCase.Failure.Message.Text, Equals, truncatedMessage)
+	c.Assert(testCase.Failure.StackTrace.Text, Equals, truncatedStackTrace)
+}
+
+func (s *MySuite) TestNUnitKeepsCDataWithinMaxFileSize(c *C) {
+	bytes, err := NewNUnitBuilder(Config{MaxFileSize: oversizedFileSize}).Encode(newOversizedReport())
+
+	c.Assert(err, Equals, nil)
+	assertWithinOversizedFileSize(c, bytes)
+	var run NUnitTestRun
+	c.Assert(xml.Unmarshal(bytes, &run), Equals, nil)
+	for _, testCase := range run.Suites[0].Suites[0].TestCases {
+		c.Assert(testCase.Failure.Message.Text, Equals, "expected <&>")
+		c.Assert(strings.HasPrefix(testCase.Failure.StackTrace.Text, "at <step> & ]]>\n"), Equals, true)
+		c.Assert(strings.Contains(testCase.Output.Text, "... [truncated "), Equals, true)
+	}
+}
+
+func (s *MySuite) TestNUnitDeterministicReportsAreIndependentOfExecution(c *C) {
+	bytes := encodeReorderedReports(c, NUnitFormat)
+
+	var run NUnitTestRun
+	c.Assert(xml.Unmarshal(bytes, &run), Equals, nil)
+
+	c.Assert(run.StartTime, Equals, "1970-01-01 00:00:00Z")
+	c.Assert(run.Duration, Equals, "0.000")
+	c.Assert(run.Suites[0].Suites[0].Name, Equals, "specs/a.spec")
+	c.Assert(run.Suites[0].Suites[1].TestCases[0].Name, Equals, "B1")
+	c.Assert(run.Suites[0].Suites[1].TestCases[0].Duration, Equals, "0.000")
+}
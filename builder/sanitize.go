@@ -0,0 +1,190 @@
+/*----------------------------------------------------------------
+ *  Copyright (c) ThoughtWorks, Inc.
+ *  Licensed under the Apache License, Version 2.0
+ *  See LICENSE in the project root for license information.
+ *----------------------------------------------------------------*/
+
+package builder
+
+import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ansiEscapes matches terminal escape sequences: CSI sequences such as colour
+// codes, OSC sequences such as hyperlinks and single character escapes.
+var ansiEscapes = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// sanitizeText strips ANSI escapes and replaces characters which are not
+// allowed in XML 1.0 with a visible "[U+XXXX]" marker. Bytes which are not
+// valid UTF-8 are replaced by U+FFFD.
+func sanitizeText(s string) string {
+	s = ansiEscapes.ReplaceAllString(s, "")
+	if isValidXMLText(s) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			b.WriteRune(utf8.RuneError)
+		case isXMLChar(r):
+			b.WriteString(s[i : i+size])
+		default:
+			fmt.Fprintf(&b, "[U+%04X]", r)
+		}
+		i += size
+	}
+	return b.String()
+}
+
+func isValidXMLText(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if !isXMLChar(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isXMLChar reports whether r is in the Char production of XML 1.0.
+func isXMLChar(r rune) bool {
+	return r == 0x09 || r == 0x0A || r == 0x0D ||
+		r >= 0x20 && r <= 0xD7FF ||
+		r >= 0xE000 && r <= 0xFFFD ||
+		r >= 0x10000 && r <= 0x10FFFF
+}
+
+// sanitize cleans every string written to the report, and marks failure
+// contents and system-out/err larger than the CDATA threshold to be written
+// as CDATA.
+func (x *XmlBuilder) sanitize() {
+	x.suites.Name = sanitizeText(x.suites.Name)
+	for i := range x.suites.Suites {
+		ts := &x.suites.Suites[i]
+		ts.Name = sanitizeText(ts.Name)
+		ts.Package = sanitizeText(ts.Package)
+		ts.Hostname = sanitizeText(ts.Hostname)
+		sanitizeProperties(ts.Properties)
+		ts.SystemOutput.Contents, ts.SystemOutput.cdata = x.sanitizeContents(ts.SystemOutput.Contents)
+		ts.SystemError.Contents, ts.SystemError.cdata = x.sanitizeContents(ts.SystemError.Contents)
+		for j := range ts.TestCases {
+			x.sanitizeTestCase(&ts.TestCases[j])
+		}
+	}
+}
+
+func (x *XmlBuilder) sanitizeTestCase(tc *JUnitTestCase) {
+	tc.Classname = sanitizeText(tc.Classname)
+	tc.Name = sanitizeText(tc.Name)
+	tc.File = sanitizeText(tc.File)
+	sanitizeProperties(tc.Properties)
+	if tc.SkipMessage != nil {
+		tc.SkipMessage.Message = sanitizeText(tc.SkipMessage.Message)
+	}
+	if tc.Failure != nil {
+		tc.Failure.Message = sanitizeText(tc.Failure.Message)
+		tc.Failure.Type = sanitizeText(tc.Failure.Type)
+		tc.Failure.Contents, tc.Failure.cdata = x.sanitizeContents(tc.Failure.Contents)
+	}
+	if tc.Error != nil {
+		tc.Error.Message = sanitizeText(tc.Error.Message)
+		tc.Error.Type = sanitizeText(tc.Error.Type)
+		tc.Error.Contents, tc.Error.cdata = x.sanitizeContents(tc.Error.Contents)
+	}
+	for _, reruns := range [][]JUnitRerun{tc.Flaky, tc.Reruns, tc.RerunErrors} {
+		for k := range reruns {
+			reruns[k].Message = sanitizeText(reruns[k].Message)
+			reruns[k].Type = sanitizeText(reruns[k].Type)
+		}
+	}
+	if tc.SystemOutput != nil {
+		tc.SystemOutput.Contents, tc.SystemOutput.cdata = x.sanitizeContents(tc.SystemOutput.Contents)
+	}
+	if tc.SystemError != nil {
+		tc.SystemError.Contents, tc.SystemError.cdata = x.sanitizeContents(tc.SystemError.Contents)
+	}
+}
+
+func sanitizeProperties(properties []JUnitProperty) {
+	for i := range properties {
+		properties[i].Name = sanitizeText(properties[i].Name)
+		properties[i].Value = sanitizeText(properties[i].Value)
+	}
+}
+
+// sanitizeContents cleans element contents and reports whether they should
+// be written as CDATA.
+func (x *XmlBuilder) sanitizeContents(contents string) (string, bool) {
+	contents = sanitizeText(contents)
+	return contents, x.config.CDataThreshold > 0 && len(contents) > x.config.CDataThreshold
+}
+
+// CDataText is element contents which are always written as CDATA, as NUnit
+// and xUnit.net do for messages, stack traces and output.
+type CDataText struct {
+	Text string `xml:",cdata"`
+}
+
+// newCDataText returns the sanitized text, or nil if it is empty.
+func newCDataText(text string) *CDataText {
+	if text == "" {
+		return nil
+	}
+	return &CDataText{Text: sanitizeText(text)}
+}
+
+// cdataContents and cdataResult are the shapes used to write contents marked
+// as CDATA.
+type cdataContents struct {
+	Contents string `xml:",cdata"`
+}
+
+type cdataResult struct {
+	Message  string `xml:"message,attr"`
+	Type     string `xml:"type,attr"`
+	Contents string `xml:",cdata"`
+}
+
+// MarshalXML writes the contents as CDATA when marked by sanitize.
+func (s SystemOut) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if s.cdata {
+		return e.EncodeElement(cdataContents{s.Contents}, start)
+	}
+	type plain SystemOut
+	return e.EncodeElement(plain(s), start)
+}
+
+// MarshalXML writes the contents as CDATA when marked by sanitize.
+func (s SystemErr) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if s.cdata {
+		return e.EncodeElement(cdataContents{s.Contents}, start)
+	}
+	type plain SystemErr
+	return e.EncodeElement(plain(s), start)
+}
+
+// MarshalXML writes the contents as CDATA when marked by sanitize.
+func (f JUnitFailure) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if f.cdata {
+		return e.EncodeElement(cdataResult{f.Message, f.Type, f.Contents}, start)
+	}
+	type plain JUnitFailure
+	return e.EncodeElement(plain(f), start)
+}
+
+// MarshalXML writes the contents as CDATA when marked by sanitize.
+func (e JUnitError) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	if e.cdata {
+		return enc.EncodeElement(cdataResult{e.Message, e.Type, e.Contents}, start)
+	}
+	type plain JUnitError
+	return enc.EncodeElement(plain(e), start)
+}
@@ -0,0 +1,71 @@
+/*----------------------------------------------------------------
+ *  Copyright (c) ThoughtWorks, Inc.
+ *  Licensed under the Apache License, Version 2.0
+ *  See LICENSE in the project root for license information.
+ *----------------------------------------------------------------*/
+
+package builder
+
+import (
+	"encoding/xml"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *MySuite) TestSanitizeTextStripsAnsiEscapes(c *C) {
+	got := sanitizeText("\x1b[31mred\x1b[0m \x1b[1;4mbold\x1b[m \x1b]8;;http://x\x07link\x1b]8;;\x1b\\ \x1bMdone")
+
+	c.Assert(got, Equals, "red bold link done")
+}
+
+func (s *MySuite) TestSanitizeTextReplacesInvalidCharacters(c *C) {
+	got := sanitizeText("a\x00b\x08c\x1fd￾e\xfff")
+
+	c.Assert(got, Equals, "a[U+0000]b[U+0008]c[U+001F]d[U+FFFE]e�f")
+}
+
+func (s *MySuite) TestSanitizeTextKeepsValidText(c *C) {
+	text := "line 1\n\tline 2\r\nüñíçødé 😀 "
+
+	c.Assert(sanitizeText(text), Equals, text)
+}
+
+func (s *MySuite) TestSanitizeContentsMarksLargeContentsAsCData(c *C) {
+	builder := NewXmlBuilder(0, Config{CDataThreshold: 5})
+
+	_, small := builder.sanitizeContents("12345")
+	_, large := builder.sanitizeContents("123456")
+
+	c.Assert(small, Equals, false)
+	c.Assert(large, Equals, true)
+}
+
+func (s *MySuite) TestSanitizeContentsWithoutCDataThreshold(c *C) {
+	builder := NewXmlBuilder(0, Config{})
+
+	_, cdata := builder.sanitizeContents("a long stack trace")
+
+	c.Assert(cdata, Equals, false)
+}
+
+func (s *MySuite) TestMarshalFailureAsCData(c *C) {
+	failure := JUnitFailure{Message: "msg", Type: "type", Contents: "a < b ]]> c", cdata: true}
+
+	bytes, err := xml.Marshal(struct {
+		XMLName xml.Name      `xml:"testcase"`
+		Failure *JUnitFailure `xml:"failure"`
+	}{Failure: &failure})
+
+	c.Assert(err, Equals, nil)
+	c.Assert(string(bytes), Equals, `<testcase><failure message="msg" type="type"><![CDATA[a < b ]]]]><![CDATA[> c]]></failure></testcase>`)
+}
+
+func (s *MySuite) TestMarshalSystemOutWithoutCData(c *C) {
+	bytes, err := xml.Marshal(struct {
+		XMLName      xml.Name `xml:"testcase"`
+		SystemOutput *SystemOut
+	}{SystemOutput: &SystemOut{Contents: "a < b"}})
+
+	c.Assert(err, Equals, nil)
+	c.Assert(string(bytes), Equals, `<testcase><system-out>a &lt; b</system-out></testcase>`)
+}
@@ -0,0 +1,179 @@
+/*----------------------------------------------------------------
+ *  Copyright (c) ThoughtWorks, Inc.
+ *  Licensed under the Apache License, Version 2.0
+ *  See LICENSE in the project root for license information.
+ *----------------------------------------------------------------*/
+
+package builder
+
+import (
+	"encoding/xml"
+	"fmt"
+	"unicode/utf8"
+)
+
+// truncatableField is a message, stack trace or output section which may be
+// truncated to keep the report within its size limits.
+type truncatableField struct {
+	value *string
+	// keepTail keeps the end of the field as well as the start, so that
+	// stack traces keep both the failure and its root cause.
+	keepTail bool
+}
+
+// truncate shortens s to keep at most limit bytes of it, followed by, or for
+// keepTail surrounding, a note of how many bytes were dropped. s is returned
+// unchanged if the note would make it longer.
+func truncate(s string, limit int, keepTail bool) string {
+	head, note, tail, ok := truncateParts(s, limit, keepTail)
+	if !ok {
+		return s
+	}
+	return head + note + tail
+}
+
+// truncateParts returns the kept head and tail of s and the note between
+// them, or false if s is returned unchanged by truncate.
+func truncateParts(s string, limit int, keepTail bool) (head, note, tail string, ok bool) {
+	if len(s) <= limit {
+		return "", "", "", false
+	}
+	if keepTail {
+		head = prefix(s, limit/2)
+		tail = suffix(s, limit-len(head))
+		note = fmt.Sprintf("\n... [truncated %d bytes] ...\n", len(s)-len(head)-len(tail))
+	} else {
+		head = prefix(s, limit)
+		note = fmt.Sprintf(" ... [truncated %d bytes]", len(s)-len(head))
+	}
+	if len(head)+len(note)+len(tail) >= len(s) {
+		return "", "", "", false
+	}
+	return head, note, tail, true
+}
+
+// prefix returns at most n bytes from the start of s without splitting a rune.
+func prefix(s string, n int) string {
+	for n > 0 && n < len(s) && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// suffix returns at most n bytes from the end of s without splitting a rune.
+func suffix(s string, n int) string {
+	start := len(s) - n
+	for start < len(s) && !utf8.RuneStart(s[start]) {
+		start++
+	}
+	return s[start:]
+}
+
+// getTruncatableFields returns the fields of the report which may be truncated.
+func (x *XmlBuilder) getTruncatableFields() []truncatableField {
+	var fields []truncatableField
+	add := func(value *string, keepTail bool) {
+		fields = append(fields, truncatableField{value: value, keepTail: keepTail})
+	}
+	for i := range x.suites.Suites {
+		ts := &x.suites.Suites[i]
+		add(&ts.SystemOutput.Contents, false)
+		add(&ts.SystemError.Contents, false)
+		for j := range ts.TestCases {
+			tc := &ts.TestCases[j]
+			if tc.SkipMessage != nil {
+				add(&tc.SkipMessage.Message, false)
+			}
+			if tc.Failure != nil {
+				add(&tc.Failure.Message, false)
+				add(&tc.Failure.Type, false)
+				add(&tc.Failure.Contents, true)
+			}
+			if tc.Error != nil {
+				add(&tc.Error.Message, false)
+				add(&tc.Error.Type, false)
+				add(&tc.Error.Contents, true)
+			}
+			for _, reruns := range [][]JUnitRerun{tc.Flaky, tc.Reruns, tc.RerunErrors} {
+				for k := range reruns {
+					add(&reruns[k].Message, false)
+					add(&reruns[k].Type, false)
+				}
+			}
+			if tc.SystemOutput != nil {
+				add(&tc.SystemOutput.Contents, false)
+			}
+			if tc.SystemError != nil {
+				add(&tc.SystemError.Contents, false)
+			}
+		}
+	}
+	return fields
+}
+
+// truncateFields applies MaxFieldSize to every truncatable field.
+func (x *XmlBuilder) truncateFields() {
+	truncateAll(x.getTruncatableFields(), x.config.MaxFieldSize)
+}
+
+// marshal writes the report within MaxFileSize, see marshalWithin.
+func (x *XmlBuilder) marshal() ([]byte, error) {
+	return marshalWithin(x.suites, x.config.MaxFileSize, x.getTruncatableFields)
+}
+
+// truncateAll truncates every field to limit bytes. Zero disables the limit.
+func truncateAll(fields []truncatableField, limit int) {
+	if limit <= 0 {
+		return
+	}
+	for _, field := range fields {
+		*field.value = truncate(*field.value, limit, field.keepTail)
+	}
+}
+
+// marshalWithin writes the report, truncating the largest fields to a common
+// limit until the report fits in maxFileSize. The limit is found by a binary
+// search which marshals the report at each step, as escaping, CDATA sections
+// and indentation make its size differ from the size of the fields. The report
+// may still exceed the limit if it is too large without any messages, stack
+// traces or output.
+func marshalWithin(report interface{}, maxFileSize int, getFields func() []truncatableField) ([]byte, error) {
+	bytes, err := xml.MarshalIndent(report, "", "\t")
+	if err != nil || maxFileSize <= 0 || len(bytes) <= maxFileSize {
+		return bytes, err
+	}
+	fields := getFields()
+	originals := make([]string, len(fields))
+	high := 0
+	for i, field := range fields {
+		originals[i] = *field.value
+		if len(originals[i]) > high {
+			high = len(originals[i])
+		}
+	}
+	marshal := func(limit int) ([]byte, error) {
+		for i, field := range fields {
+			*field.value = truncate(originals[i], limit, field.keepTail)
+		}
+		return xml.MarshalIndent(report, "", "\t")
+	}
+	// The untruncated report is too large, so the limit is below the length of
+	// the longest field.
+	low, high, last := 0, high-1, -1
+	for low < high {
+		mid := (low + high + 1) / 2
+		if bytes, err = marshal(mid); err != nil {
+			return nil, err
+		}
+		last = mid
+		if len(bytes) <= maxFileSize {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	if last != low {
+		return marshal(low)
+	}
+	return bytes, nil
+}
@@ -0,0 +1,80 @@
+/*----------------------------------------------------------------
+ *  Copyright (c) ThoughtWorks, Inc.
+ *  Licensed under the Apache License, Version 2.0
+ *  See LICENSE in the project root for license information.
+ *----------------------------------------------------------------*/
+
+package builder
+
+import (
+	"encoding/xml"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *MySuite) TestTruncateKeepsShortText(c *C) {
+	c.Assert(truncate("short", 5, false), Equals, "short")
+	c.Assert(truncate("short", 5, true), Equals, "short")
+}
+
+func (s *MySuite) TestTruncateKeepsHead(c *C) {
+	got := truncate(strings.Repeat("a", 40)+strings.Repeat("b", 60), 40, false)
+
+	c.Assert(got, Equals, strings.Repeat("a", 40)+" ... [truncated 60 bytes]")
+}
+
+func (s *MySuite) TestTruncateKeepsHeadAndTail(c *C) {
+	got := truncate(strings.Repeat("a", 20)+strings.Repeat("b", 60)+strings.Repeat("c", 20), 40, true)
+
+	c.Assert(got, Equals, strings.Repeat("a", 20)+"\n... [truncated 60 bytes] ...\n"+strings.Repeat("c", 20))
+}
+
+func (s *MySuite) TestTruncateDoesNotSplitRunes(c *C) {
+	got := truncate("ab€cdefghijklmnopqrstuvwxyz0123456789", 4, false)
+
+	c.Assert(got, Equals, "ab ... [truncated 37 bytes]")
+}
+
+func (s *MySuite) TestTruncateDoesNotGrowText(c *C) {
+	text := strings.Repeat("a", 20)
+
+	c.Assert(truncate(text, 10, false), Equals, text)
+}
+
+type sizedReport struct {
+	XMLName xml.Name  `xml:"report"`
+	Data    CDataText `xml:"data"`
+	Text    string    `xml:"text"`
+}
+
+func (s *MySuite) TestMarshalWithinMeasuresMarshalledSize(c *C) {
+	for _, content := range []string{"<&>]]>", "\n", "a"} {
+		report := &sizedReport{Data: CDataText{Text: strings.Repeat(content, 2000)}, Text: strings.Repeat(content, 2000)}
+		getFields := func() []truncatableField {
+			return []truncatableField{{value: &report.Data.Text, keepTail: true}, {value: &report.Text}}
+		}
+
+		bytes, err := marshalWithin(report, 1000, getFields)
+
+		c.Assert(err, Equals, nil)
+		c.Assert(len(bytes) <= 1000, Equals, true, Commentf("report of %q is %d bytes", content, len(bytes)))
+		c.Assert(len(bytes) > 900, Equals, true, Commentf("report of %q is %d bytes", content, len(bytes)))
+		var decoded sizedReport
+		c.Assert(xml.Unmarshal(bytes, &decoded), Equals, nil)
+		c.Assert(decoded.Data.Text, Equals, report.Data.Text)
+		c.Assert(decoded.Text, Equals, report.Text)
+	}
+}
+
+func (s *MySuite) TestMarshalWithinKeepsReportWithinLimit(c *C) {
+	report := &sizedReport{Data: CDataText{Text: "data"}, Text: "text"}
+	getFields := func() []truncatableField {
+		return []truncatableField{{value: &report.Data.Text}, {value: &report.Text}}
+	}
+
+	bytes, err := marshalWithin(report, 1000, getFields)
+
+	c.Assert(err, Equals, nil)
+	c.Assert(string(bytes), Equals, "<report>\n\t<data><![CDATA[data]]></data>\n\t<text>text</text>\n</report>")
+}
@@ -0,0 +1,513 @@
+/*----------------------------------------------------------------
+ *  Copyright (c) ThoughtWorks, Inc.
+ *  Licensed under the Apache License, Version 2.0
+ *  See LICENSE in the project root for license information.
+ *----------------------------------------------------------------*/
+
+package builder
+
+import (
+	"crypto/sha1"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/getgauge/xml-report/model"
+)
+
+// TrxFormat is the name of the Visual Studio TRX format.
+const TrxFormat = "trx"
+
+const (
+	trxTimeFormat = "2006-01-02T15:04:05.0000000Z07:00"
+	// trxUnitTestType and trxTestListId are the ids Visual Studio uses for
+	// unit tests and for the "Results Not in a List" test list.
+	trxUnitTestType     = "13cdc9d9-ddb5-4fa4-a97d-d965ccfc6d4b"
+	trxTestListId       = "8c84fa94-04c1-424b-9868-57a2d4851a1d"
+	trxTestListName     = "Results Not in a List"
+	trxAdapterTypeName  = "executor://gauge/xml-report"
+	trxParseErrorsName  = "Parse/Validation Errors"
+	trxCompletedOutcome = "Completed"
+	trxSettingsName     = "default"
+	// trxDeploymentRoot is the run deployment directory, next to the report,
+	// holding the result files of each result in In/<relativeResultsDirectory>
+	// and those of the run in In.
+	trxDeploymentRoot = "trx"
+)
+
+// Values of the TRX outcome attribute of a result.
+const (
+	trxPassed      = "Passed"
+	trxFailed      = "Failed"
+	trxError       = "Error"
+	trxNotExecuted = "NotExecuted"
+)
+
+// trxNamespace is the UUID namespace for URLs, which the name-based GUIDs of
+// the report are derived in.
+var trxNamespace = []byte{0x6b, 0xa7, 0xb8, 0x11, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+
+// TrxTestRun is the root element of a Visual Studio TRX file.
+type TrxTestRun struct {
+	XMLName         xml.Name            `xml:"http://microsoft.com/schemas/VisualStudio/TeamTest/2010 TestRun"`
+	Id              string              `xml:"id,attr"`
+	Name            string              `xml:"name,attr"`
+	Times           TrxTimes            `xml:"Times"`
+	TestSettings    TrxTestSettings     `xml:"TestSettings"`
+	Results         []TrxUnitTestResult `xml:"Results>UnitTestResult"`
+	TestDefinitions []TrxUnitTest       `xml:"TestDefinitions>UnitTest"`
+	TestEntries     []TrxTestEntry      `xml:"TestEntries>TestEntry"`
+	TestLists       []TrxTestList       `xml:"TestLists>TestList"`
+	ResultSummary   TrxResultSummary    `xml:"ResultSummary"`
+}
+
+// TrxTimes are the start and end times of the run.
+type TrxTimes struct {
+	Creation string `xml:"creation,attr"`
+	Queuing  string `xml:"queuing,attr"`
+	Start    string `xml:"start,attr"`
+	Finish   string `xml:"finish,attr"`
+}
+
+// TrxTestSettings names the deployment directory the result files are
+// resolved in.
+type TrxTestSettings struct {
+	Name       string        `xml:"name,attr"`
+	Id         string        `xml:"id,attr"`
+	Deployment TrxDeployment `xml:"Deployment"`
+}
+
+type TrxDeployment struct {
+	RunDeploymentRoot string `xml:"runDeploymentRoot,attr"`
+}
+
+// TrxUnitTestResult is the result of a scenario, of one data table row of a
+// table driven scenario, or of a spec that failed to run.
+type TrxUnitTestResult struct {
+	ExecutionId              string          `xml:"executionId,attr"`
+	TestId                   string          `xml:"testId,attr"`
+	TestName                 string          `xml:"testName,attr"`
+	ComputerName             string          `xml:"computerName,attr"`
+	Duration                 string          `xml:"duration,attr"`
+	StartTime                string          `xml:"startTime,attr"`
+	EndTime                  string          `xml:"endTime,attr"`
+	TestType                 string          `xml:"testType,attr"`
+	Outcome                  string          `xml:"outcome,attr"`
+	TestListId               string          `xml:"testListId,attr"`
+	RelativeResultsDirectory string          `xml:"relativeResultsDirectory,attr"`
+	Output                   *TrxOutput      `xml:"Output,omitempty"`
+	ResultFiles              []TrxResultFile `xml:"ResultFiles>ResultFile,omitempty"`
+}
+
+// TrxOutput holds the messages written by a test and its failure.
+type TrxOutput struct {
+	StdOut    string        `xml:"StdOut,omitempty"`
+	ErrorInfo *TrxErrorInfo `xml:"ErrorInfo,omitempty"`
+}
+
+// TrxErrorInfo holds the failure message and stack trace of a test, or the
+// reason why it was skipped.
+type TrxErrorInfo struct {
+	Message    string `xml:"Message"`
+	StackTrace string `xml:"StackTrace,omitempty"`
+}
+
+// TrxResultFile is a screenshot, relative to the In directory of the run
+// deployment directory for the run, and to its result's directory in it for
+// a result.
+type TrxResultFile struct {
+	Path string `xml:"path,attr"`
+}
+
+// TrxUnitTest defines the test a result is reported for.
+type TrxUnitTest struct {
+	Name       string            `xml:"name,attr"`
+	Storage    string            `xml:"storage,attr"`
+	Id         string            `xml:"id,attr"`
+	Categories []TrxTestCategory `xml:"TestCategory>TestCategoryItem,omitempty"`
+	Execution  TrxExecution      `xml:"Execution"`
+	TestMethod TrxTestMethod     `xml:"TestMethod"`
+}
+
+// TrxTestCategory is a tag of the test.
+type TrxTestCategory struct {
+	TestCategory string `xml:"TestCategory,attr"`
+}
+
+type TrxExecution struct {
+	Id string `xml:"id,attr"`
+}
+
+// TrxTestMethod names the spec file, the spec and the scenario of a test.
+type TrxTestMethod struct {
+	CodeBase        string `xml:"codeBase,attr"`
+	AdapterTypeName string `xml:"adapterTypeName,attr"`
+	ClassName       string `xml:"className,attr"`
+	Name            string `xml:"name,attr"`
+}
+
+// TrxTestEntry links a test to its result.
+type TrxTestEntry struct {
+	TestId      string `xml:"testId,attr"`
+	ExecutionId string `xml:"executionId,attr"`
+	TestListId  string `xml:"testListId,attr"`
+}
+
+type TrxTestList struct {
+	Name string `xml:"name,attr"`
+	Id   string `xml:"id,attr"`
+}
+
+// TrxResultSummary holds the counts of the results, and reports the suite
+// hook failures as run infos.
+type TrxResultSummary struct {
+	Outcome     string          `xml:"outcome,attr"`
+	Counters    TrxCounters     `xml:"Counters"`
+	Output      *TrxOutput      `xml:"Output,omitempty"`
+	RunInfos    []TrxRunInfo    `xml:"RunInfos>RunInfo,omitempty"`
+	ResultFiles []TrxResultFile `xml:"ResultFiles>ResultFile,omitempty"`
+}
+
+// TrxCounters are the counts of the results by outcome.
+type TrxCounters struct {
+	Total               int `xml:"total,attr"`
+	Executed            int `xml:"executed,attr"`
+	Passed              int `xml:"passed,attr"`
+	Failed              int `xml:"failed,attr"`
+	Error               int `xml:"error,attr"`
+	Timeout             int `xml:"timeout,attr"`
+	Aborted             int `xml:"aborted,attr"`
+	Inconclusive        int `xml:"inconclusive,attr"`
+	PassedButRunAborted int `xml:"passedButRunAborted,attr"`
+	NotRunnable         int `xml:"notRunnable,attr"`
+	NotExecuted         int `xml:"notExecuted,attr"`
+	Disconnected        int `xml:"disconnected,attr"`
+	Warning             int `xml:"warning,attr"`
+	Completed           int `xml:"completed,attr"`
+	InProgress          int `xml:"inProgress,attr"`
+	Pending             int `xml:"pending,attr"`
+}
+
+// TrxRunInfo is a message about the run, e.g. a failed suite hook.
+type TrxRunInfo struct {
+	ComputerName string `xml:"computerName,attr"`
+	Outcome      string `xml:"outcome,attr"`
+	Timestamp    string `xml:"timestamp,attr"`
+	Text         string `xml:"Text"`
+}
+
+// TrxBuilder writes the Visual Studio TRX report of a suite. Each scenario,
+// and each data table row of a table driven scenario, is a UnitTestResult
+// whose test id is derived from the spec file and the scenario heading, so
+// that it is the same in every run.
+type TrxBuilder struct {
+	config       Config
+	run          TrxTestRun
+	computerName string
+	ids          map[string]bool
+	attachments  []Attachment
+}
+
+func NewTrxBuilder(config Config) *TrxBuilder {
+	return &TrxBuilder{config: config}
+}
+
+// Name returns TrxFormat.
+func (t *TrxBuilder) Name() string {
+	return TrxFormat
+}
+
+// FileExtension returns ".trx".
+func (t *TrxBuilder) FileExtension() string {
+	return ".trx"
+}
+
+// Attachments returns the screenshot files referenced by the last generated report.
+func (t *TrxBuilder) Attachments() []Attachment {
+	return t.attachments
+}
+
+// Encode returns the TRX report of the suite.
+func (t *TrxBuilder) Encode(report *model.Report) ([]byte, error) {
+	t.ids = map[string]bool{}
+	t.attachments = nil
+	t.computerName = getHostName()
+	specs := report.Specs
+	if t.config.Deterministic {
+		t.computerName = deterministicHostname
+		specs = sortSpecs(specs)
+	}
+	start, finish, _ := t.getTimes(report.StartTime, report.Duration)
+	name := sanitizeText(report.ProjectName)
+	runId := trxGuid("run", report.ProjectName, start)
+	t.run = TrxTestRun{
+		Id:    runId,
+		Name:  name,
+		Times: TrxTimes{Creation: start, Queuing: start, Start: start, Finish: finish},
+		TestSettings: TrxTestSettings{
+			Name:       trxSettingsName,
+			Id:         trxGuid("settings", runId),
+			Deployment: TrxDeployment{RunDeploymentRoot: trxDeploymentRoot},
+		},
+		TestLists: []TrxTestList{{Name: trxTestListName, Id: trxTestListId}},
+	}
+	output := append([]string{}, report.Output...)
+	for _, spec := range specs {
+		t.getSpecContent(spec)
+		output = append(output, spec.Output...)
+	}
+	t.run.ResultSummary = t.getResultSummary(report, output)
+	truncateAll(t.getTruncatableFields(), t.config.MaxFieldSize)
+	return marshalWithin(t.run, t.config.MaxFileSize, t.getTruncatableFields)
+}
+
+// getSpecContent adds a result for each scenario of the spec. Parse errors,
+// validation errors outside any scenario and spec hook failures are
+// reported as errored results named after the spec.
+func (t *TrxBuilder) getSpecContent(spec *model.Spec) {
+	classname := getSpecClassname(spec, t.config.ClassnameStrategy)
+	if spec.HasParseErrors() {
+		result := t.addResult(spec, classname, spec.Name, spec.Tags, spec.StartTime, spec.Duration, trxError, spec.File, trxParseErrorsName)
+		result.Output = &TrxOutput{ErrorInfo: &TrxErrorInfo{Message: sanitizeText(joinErrors(spec.Errors))}}
+		return
+	}
+	if len(spec.ValidationErrors) > 0 {
+		result := t.addResult(spec, classname, spec.Name, spec.Tags, spec.StartTime, 0, trxError, spec.File, validationErrorMsg)
+		result.Output = &TrxOutput{ErrorInfo: &TrxErrorInfo{Message: sanitizeText(joinErrors(spec.ValidationErrors))}}
+	}
+	t.getSpecHookContent(spec, classname, spec.BeforeHooks)
+	start := spec.StartTime
+	for _, scenario := range spec.Scenarios {
+		t.getScenarioContent(spec, classname, scenario, start)
+		start = start.Add(scenario.Duration)
+	}
+	t.getSpecHookContent(spec, classname, spec.AfterHooks)
+}
+
+// getSpecHookContent adds an errored result for each failed spec hook. For
+// data driven specs the name carries the table row the hook failed for.
+func (t *TrxBuilder) getSpecHookContent(spec *model.Spec, classname string, hooks []*model.Hook) {
+	for _, hook := range hooks {
+		result := t.addResult(spec, classname, hook.Name, spec.Tags, spec.StartTime, 0, trxError, spec.File, hook.Name)
+		result.Output = &TrxOutput{ErrorInfo: newTrxErrorInfo(hook.Failure.Message, hook.Failure.StackTrace)}
+		result.ResultFiles = t.getResultFiles(result.RelativeResultsDirectory, hook.Screenshot)
+	}
+}
+
+// getScenarioContent adds the result of the scenario, which started at start.
+func (t *TrxBuilder) getScenarioContent(spec *model.Spec, classname string, scenario *model.Scenario, start time.Time) {
+	idParts := []string{spec.File, scenario.Heading}
+	if scenario.SpecRow != nil {
+		idParts = append(idParts, fmt.Sprintf("SpecRow: %d", scenario.SpecRow.Index+1))
+	}
+	if scenario.ScenarioRow != nil {
+		idParts = append(idParts, fmt.Sprintf("ScnRow: %d", scenario.ScenarioRow.Index+1))
+	}
+	output := &TrxOutput{}
+	outcome := trxPassed
+	if scenario.Status == model.Failed {
+		outcome = trxFailed
+		if scenario.Errored() {
+			outcome = trxError
+		}
+		output.ErrorInfo = newTrxErrorInfo(scenario.Failures.Summary())
+	} else if len(scenario.Errors) > 0 {
+		outcome = trxError
+		output.ErrorInfo = newTrxErrorInfo(joinErrors(scenario.Errors), "")
+	} else if scenario.Status == model.Skipped {
+		outcome = trxNotExecuted
+		if len(scenario.SkipReasons) > 0 {
+			output.ErrorInfo = newTrxErrorInfo(strings.Join(scenario.SkipReasons, "\n"), "")
+		}
+	}
+	tags := append(append([]string{}, spec.Tags...), scenario.Tags...)
+	result := t.addResult(spec, classname, scenario.Name, tags, start, scenario.Duration, outcome, idParts...)
+	var messages []string
+	for _, o := range scenario.Output {
+		if o.Screenshot != "" {
+			result.ResultFiles = append(result.ResultFiles, t.getResultFiles(result.RelativeResultsDirectory, o.Screenshot)...)
+		} else {
+			messages = append(messages, o.Message)
+		}
+	}
+	output.StdOut = sanitizeText(strings.Join(messages, "\n"))
+	if output.StdOut != "" || output.ErrorInfo != nil {
+		result.Output = output
+	}
+}
+
+// addResult adds a result with its test definition and entry. The test id
+// is derived from idParts.
+func (t *TrxBuilder) addResult(spec *model.Spec, classname, name string, tags []string, start time.Time, duration time.Duration, outcome string, idParts ...string) *TrxUnitTestResult {
+	testId := t.getTestId(idParts...)
+	executionId := trxGuid("execution", testId)
+	name = sanitizeText(name)
+	startTime, endTime, trxDuration := t.getTimes(start, duration)
+	t.run.TestDefinitions = append(t.run.TestDefinitions, TrxUnitTest{
+		Name:       name,
+		Storage:    sanitizeText(spec.File),
+		Id:         testId,
+		Categories: getTrxCategories(tags),
+		Execution:  TrxExecution{Id: executionId},
+		TestMethod: TrxTestMethod{
+			CodeBase:        sanitizeText(spec.File),
+			AdapterTypeName: trxAdapterTypeName,
+			ClassName:       sanitizeText(classname),
+			Name:            name,
+		},
+	})
+	t.run.TestEntries = append(t.run.TestEntries, TrxTestEntry{TestId: testId, ExecutionId: executionId, TestListId: trxTestListId})
+	t.run.Results = append(t.run.Results, TrxUnitTestResult{
+		ExecutionId:              executionId,
+		TestId:                   testId,
+		TestName:                 name,
+		ComputerName:             t.computerName,
+		Duration:                 trxDuration,
+		StartTime:                startTime,
+		EndTime:                  endTime,
+		TestType:                 trxUnitTestType,
+		Outcome:                  outcome,
+		TestListId:               trxTestListId,
+		RelativeResultsDirectory: executionId,
+	})
+	return &t.run.Results[len(t.run.Results)-1]
+}
+
+// getResultSummary counts the results, and reports failed suite hooks as run
+// infos and the output of the suite and spec hooks as the run's output.
+func (t *TrxBuilder) getResultSummary(report *model.Report, output []string) TrxResultSummary {
+	summary := TrxResultSummary{Outcome: trxCompletedOutcome}
+	counters := &summary.Counters
+	for _, result := range t.run.Results {
+		counters.Total++
+		switch result.Outcome {
+		case trxPassed:
+			counters.Passed++
+		case trxFailed:
+			counters.Failed++
+		case trxError:
+			counters.Error++
+		case trxNotExecuted:
+			counters.NotExecuted++
+		}
+	}
+	counters.Executed = counters.Total - counters.NotExecuted
+	timestamp, _, _ := t.getTimes(report.StartTime, 0)
+	for _, hook := range []*model.Hook{report.BeforeHook, report.AfterHook} {
+		if hook == nil {
+			continue
+		}
+		summary.RunInfos = append(summary.RunInfos, TrxRunInfo{
+			ComputerName: t.computerName,
+			Outcome:      trxError,
+			Timestamp:    timestamp,
+			Text:         sanitizeText(strings.TrimSpace(hook.Failure.Message + "\n" + hook.Failure.StackTrace)),
+		})
+		summary.ResultFiles = append(summary.ResultFiles, t.getResultFiles("", hook.Screenshot)...)
+	}
+	if counters.Failed+counters.Error > 0 || len(summary.RunInfos) > 0 {
+		summary.Outcome = trxFailed
+	}
+	if stdOut := sanitizeText(strings.Join(output, "\n")); stdOut != "" {
+		summary.Output = &TrxOutput{StdOut: stdOut}
+	}
+	return summary
+}
+
+// getTestId returns the GUID derived from the given parts. Tests which would
+// get the same id, e.g. scenarios with the same heading, are told apart by
+// their position.
+func (t *TrxBuilder) getTestId(parts ...string) string {
+	key := strings.Join(parts, "/")
+	id := key
+	for n := 2; t.ids[id]; n++ {
+		id = fmt.Sprintf("%s/%d", key, n)
+	}
+	t.ids[id] = true
+	return trxGuid(id)
+}
+
+// getResultFiles records the given screenshot files, to be copied to the
+// resultsDir of the run deployment directory's In directory, and returns
+// them as result files.
+func (t *TrxBuilder) getResultFiles(resultsDir string, files ...string) []TrxResultFile {
+	var resultFiles []TrxResultFile
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+		name := path.Base(AttachmentPath(file))
+		t.attachments = append(t.attachments, Attachment{File: file, Path: path.Join(trxDeploymentRoot, "In", resultsDir, name)})
+		resultFiles = append(resultFiles, TrxResultFile{Path: name})
+	}
+	return resultFiles
+}
+
+// getTimes returns the start time, end time and duration attributes. They
+// are fixed in deterministic mode.
+func (t *TrxBuilder) getTimes(start time.Time, duration time.Duration) (string, string, string) {
+	if t.config.Deterministic {
+		start, duration = time.Unix(0, 0), 0
+	}
+	return start.UTC().Format(trxTimeFormat), start.Add(duration).UTC().Format(trxTimeFormat), formatTrxDuration(duration)
+}
+
+// getTruncatableFields returns the fields of the report which may be truncated.
+func (t *TrxBuilder) getTruncatableFields() []truncatableField {
+	var fields []truncatableField
+	addOutput := func(output *TrxOutput) {
+		if output == nil {
+			return
+		}
+		fields = append(fields, truncatableField{value: &output.StdOut})
+		if output.ErrorInfo != nil {
+			fields = append(fields, truncatableField{value: &output.ErrorInfo.Message},
+				truncatableField{value: &output.ErrorInfo.StackTrace, keepTail: true})
+		}
+	}
+	for i := range t.run.Results {
+		addOutput(t.run.Results[i].Output)
+	}
+	addOutput(t.run.ResultSummary.Output)
+	for i := range t.run.ResultSummary.RunInfos {
+		fields = append(fields, truncatableField{value: &t.run.ResultSummary.RunInfos[i].Text, keepTail: true})
+	}
+	return fields
+}
+
+// getTrxCategories returns the tags as test categories, leaving out duplicates.
+func getTrxCategories(tags []string) []TrxTestCategory {
+	var categories []TrxTestCategory
+	for _, tag := range uniqueTags(tags) {
+		categories = append(categories, TrxTestCategory{TestCategory: sanitizeText(tag)})
+	}
+	return categories
+}
+
+func newTrxErrorInfo(message, stackTrace string) *TrxErrorInfo {
+	return &TrxErrorInfo{Message: sanitizeText(message), StackTrace: sanitizeText(stackTrace)}
+}
+
+// trxGuid returns the name-based (version 5) UUID of the parts joined by "/".
+func trxGuid(parts ...string) string {
+	h := sha1.New()
+	h.Write(trxNamespace)
+	h.Write([]byte(strings.Join(parts, "/")))
+	sum := h.Sum(nil)[:16]
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// formatTrxDuration formats the duration as hh:mm:ss.fffffff.
+func formatTrxDuration(d time.Duration) string {
+	hours := d / time.Hour
+	d -= hours * time.Hour
+	minutes := d / time.Minute
+	d -= minutes * time.Minute
+	seconds := d / time.Second
+	d -= seconds * time.Second
+	return fmt.Sprintf("%02d:%02d:%02d.%07d", hours, minutes, seconds, d/100)
+}
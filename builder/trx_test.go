@@ -0,0 +1,252 @@
+/*----------------------------------------------------------------
+ *  Copyright (c) ThoughtWorks, Inc.
+ *  Licensed under the Apache License, Version 2.0
+ *  See LICENSE in the project root for license information.
+ *----------------------------------------------------------------*/
+
+package builder
+
+import (
+	"encoding/xml"
+	"path"
+	"time"
+
+	"github.com/getgauge/xml-report/model"
+	. "gopkg.in/check.v1"
+)
+
+func encodeTrx(c *C, config Config, report *model.Report) TrxTestRun {
+	bytes, err := NewTrxBuilder(config).Encode(report)
+	c.Assert(err, Equals, nil)
+
+	var run TrxTestRun
+	c.Assert(xml.Unmarshal(bytes, &run), Equals, nil)
+	return run
+}
+
+func (s *MySuite) TestNewEncoderForTrx(c *C) {
+	encoder, err := NewEncoder(TrxFormat, Config{})
+
+	c.Assert(err, Equals, nil)
+	c.Assert(encoder.Name(), Equals, "trx")
+	c.Assert(encoder.FileExtension(), Equals, ".trx")
+}
+
+func (s *MySuite) TestTrxGuidIsAVersion5Uuid(c *C) {
+	c.Assert(trxGuid("www.example.com"), Equals, "b63cdfa4-3df9-568e-97ae-006c5b8fd652")
+}
+
+func (s *MySuite) TestFormatTrxDuration(c *C) {
+	c.Assert(formatTrxDuration(0), Equals, "00:00:00.0000000")
+	c.Assert(formatTrxDuration(time.Hour+2*time.Minute+3*time.Second+456789*time.Microsecond), Equals, "01:02:03.4567890")
+}
+
+func (s *MySuite) TestTrxReportsScenarioResults(c *C) {
+	run := encodeTrx(c, Config{}, newCheckoutReport())
+
+	c.Assert(run.Name, Equals, "shop")
+	c.Assert(run.Times.Start, Equals, "2021-03-04T05:06:07.0000000Z")
+	c.Assert(run.Times.Finish, Equals, "2021-03-04T05:06:10.0000000Z")
+	c.Assert(len(run.Results), Equals, 4)
+	c.Assert(len(run.TestDefinitions), Equals, 4)
+	c.Assert(len(run.TestEntries), Equals, 4)
+
+	passed := run.Results[0]
+	c.Assert(passed.TestName, Equals, "Pay")
+	c.Assert(passed.Outcome, Equals, "Passed")
+	c.Assert(passed.TestId, Equals, trxGuid("specs/checkout.spec", "Pay"))
+	c.Assert(passed.Duration, Equals, "00:00:01.5000000")
+	c.Assert(passed.EndTime, Equals, "2021-03-04T05:06:08.5000000Z")
+	c.Assert(passed.Output, IsNil)
+	c.Assert(run.Results[1].Outcome, Equals, "Failed")
+	c.Assert(run.Results[1].StartTime, Equals, "2021-03-04T05:06:08.5000000Z")
+	c.Assert(*run.Results[1].Output.ErrorInfo, Equals, TrxErrorInfo{Message: "boom", StackTrace: "trace"})
+	c.Assert(run.Results[2].Outcome, Equals, "NotExecuted")
+	c.Assert(run.Results[2].Output.ErrorInfo.Message, Equals, "not ready")
+	c.Assert(run.Results[3].Outcome, Equals, "Error")
+
+	definition := run.TestDefinitions[0]
+	c.Assert(definition.Id, Equals, passed.TestId)
+	c.Assert(definition.Execution.Id, Equals, passed.ExecutionId)
+	c.Assert(definition.Storage, Equals, "specs/checkout.spec")
+	c.Assert(definition.TestMethod.ClassName, Equals, "Checkout")
+	c.Assert(definition.Categories, DeepEquals, []TrxTestCategory{{TestCategory: "checkout"}, {TestCategory: "smoke"}})
+
+	c.Assert(run.ResultSummary.Outcome, Equals, "Failed")
+	c.Assert(run.ResultSummary.Counters.Total, Equals, 4)
+	c.Assert(run.ResultSummary.Counters.Executed, Equals, 3)
+	c.Assert(run.ResultSummary.Counters.Passed, Equals, 1)
+	c.Assert(run.ResultSummary.Counters.Failed, Equals, 1)
+	c.Assert(run.ResultSummary.Counters.Error, Equals, 1)
+	c.Assert(run.ResultSummary.Counters.NotExecuted, Equals, 1)
+}
+
+func (s *MySuite) TestTrxTestIdsAreStable(c *C) {
+	row := func(index int) *model.TableRow {
+		return &model.TableRow{Index: index, Headers: []string{"card"}, Cells: []string{"visa"}}
+	}
+	report := &model.Report{Specs: []*model.Spec{{
+		Name: "Payment", File: "specs/payment.spec",
+		Scenarios: []*model.Scenario{
+			{Name: "Pay | SpecRow: 1", Heading: "Pay", SpecRow: row(0)},
+			{Name: "Pay | SpecRow: 2", Heading: "Pay", SpecRow: row(1)},
+			{Name: "Log out", Heading: "Log out"},
+			{Name: "Log out", Heading: "Log out"},
+		},
+	}}}
+
+	first := encodeTrx(c, Config{}, report)
+	second := encodeTrx(c, Config{}, report)
+
+	c.Assert(first.Results[0].TestId, Equals, trxGuid("specs/payment.spec", "Pay", "SpecRow: 1"))
+	c.Assert(first.Results[1].TestId, Equals, trxGuid("specs/payment.spec", "Pay", "SpecRow: 2"))
+	c.Assert(first.Results[3].TestId, Equals, trxGuid("specs/payment.spec", "Log out", "2"))
+	for i := range first.Results {
+		c.Assert(first.Results[i].TestId, Equals, second.Results[i].TestId)
+		c.Assert(first.Results[i].ExecutionId, Not(Equals), first.Results[i].TestId)
+	}
+	c.Assert(first.Id, Equals, second.Id)
+}
+
+func (s *MySuite) TestTrxTestIdsOfDifferentPartsDiffer(c *C) {
+	builder := NewTrxBuilder(Config{})
+	builder.ids = map[string]bool{}
+
+	first := builder.getTestId("a/b", "c")
+	second := builder.getTestId("a", "b/c")
+
+	c.Assert(first, Not(Equals), second)
+	c.Assert(first, Equals, trxGuid("a/b/c"))
+}
+
+func (s *MySuite) TestTrxReportsHookFailuresAndErrors(c *C) {
+	report := &model.Report{
+		AfterHook: &model.Hook{Name: "AfterSuite", Failure: model.Failure{Message: "Post Hook Failure: 'db down'", StackTrace: "suite trace"}, Screenshot: "suite.png", TableRow: -1},
+		Output:    []string{"suite message"},
+		Specs: []*model.Spec{
+			{
+				Name: "Payment", File: "specs/payment.spec", Output: []string{"spec message"},
+				ValidationErrors: []model.Error{{Type: model.ValidationError, File: "specs/payment.spec", Line: 4, Message: "Step implementation not found"}},
+				AfterHooks:       []*model.Hook{{Name: "AfterSpec | SpecRow: 2", Failure: model.Failure{Message: "after", StackTrace: "after trace"}, Screenshot: "spec.png", TableRow: 1}},
+			},
+			{
+				Name: "Broken", File: "specs/broken.spec",
+				Errors: []model.Error{{Type: model.ParseError, Message: "missing heading"}},
+			},
+		},
+	}
+	builder := NewTrxBuilder(Config{})
+	bytes, err := builder.Encode(report)
+	c.Assert(err, Equals, nil)
+
+	var run TrxTestRun
+	c.Assert(xml.Unmarshal(bytes, &run), Equals, nil)
+
+	c.Assert(len(run.Results), Equals, 3)
+	c.Assert(run.Results[0].TestName, Equals, "Payment")
+	c.Assert(run.Results[0].Outcome, Equals, "Error")
+	c.Assert(run.Results[0].Output.ErrorInfo.Message, Equals, "[Validation Error] specs/payment.spec:4: Step implementation not found")
+	c.Assert(run.Results[1].TestName, Equals, "AfterSpec | SpecRow: 2")
+	c.Assert(*run.Results[1].Output.ErrorInfo, Equals, TrxErrorInfo{Message: "after", StackTrace: "after trace"})
+	c.Assert(run.Results[1].ResultFiles, DeepEquals, []TrxResultFile{{Path: "spec.png"}})
+	c.Assert(run.Results[2].TestName, Equals, "Broken")
+	c.Assert(run.Results[2].Output.ErrorInfo.Message, Equals, "[Parse Error] missing heading")
+
+	summary := run.ResultSummary
+	c.Assert(summary.Outcome, Equals, "Failed")
+	c.Assert(summary.Counters.Error, Equals, 3)
+	c.Assert(summary.Output.StdOut, Equals, "suite message\nspec message")
+	c.Assert(len(summary.RunInfos), Equals, 1)
+	c.Assert(summary.RunInfos[0].Outcome, Equals, "Error")
+	c.Assert(summary.RunInfos[0].Text, Equals, "Post Hook Failure: 'db down'\nsuite trace")
+	c.Assert(summary.ResultFiles, DeepEquals, []TrxResultFile{{Path: "suite.png"}})
+	c.Assert(builder.Attachments(), DeepEquals, []Attachment{
+		{File: "spec.png", Path: "trx/In/" + run.Results[1].RelativeResultsDirectory + "/spec.png"},
+		{File: "suite.png", Path: "trx/In/suite.png"},
+	})
+}
+
+func (s *MySuite) TestTrxResultFilesResolveToCopiedAttachments(c *C) {
+	report := &model.Report{
+		BeforeHook: &model.Hook{Name: "BeforeSuite", Failure: model.Failure{Message: "db down"}, Screenshot: "/tmp/suite.png", TableRow: -1},
+		Specs: []*model.Spec{{
+			Name: "Payment", File: "specs/payment.spec",
+			Scenarios: []*model.Scenario{
+				{Name: "Pay", Heading: "Pay", Output: []model.Output{{Screenshot: "/tmp/a/paid.png"}, {Screenshot: "/tmp/b/paid.png"}}},
+				{Name: "Refund", Heading: "Refund", Output: []model.Output{{Screenshot: "refund.png"}}},
+			},
+		}},
+	}
+	builder := NewTrxBuilder(Config{})
+	bytes, err := builder.Encode(report)
+	c.Assert(err, Equals, nil)
+
+	var run TrxTestRun
+	c.Assert(xml.Unmarshal(bytes, &run), Equals, nil)
+
+	// Visual Studio and Azure DevOps resolve result files in the In directory
+	// of the run deployment root, next to the report.
+	in := path.Join(run.TestSettings.Deployment.RunDeploymentRoot, "In")
+	var resolved []string
+	for _, result := range run.Results {
+		for _, file := range result.ResultFiles {
+			resolved = append(resolved, path.Join(in, result.RelativeResultsDirectory, file.Path))
+		}
+	}
+	for _, file := range run.ResultSummary.ResultFiles {
+		resolved = append(resolved, path.Join(in, file.Path))
+	}
+	var copied []string
+	for _, attachment := range builder.Attachments() {
+		copied = append(copied, attachment.Path)
+	}
+	c.Assert(run.TestSettings.Deployment.RunDeploymentRoot, Equals, "trx")
+	c.Assert(len(resolved), Equals, 4)
+	c.Assert(resolved, DeepEquals, copied)
+	c.Assert(resolved[0], Not(Equals), resolved[1])
+}
+
+func (s *MySuite) TestTrxReportsOutputAndScreenshots(c *C) {
+	builder := NewTrxBuilder(Config{})
+
+	bytes, err := builder.Encode(newOutputReport())
+
+	c.Assert(err, Equals, nil)
+	var run TrxTestRun
+	c.Assert(xml.Unmarshal(bytes, &run), Equals, nil)
+	result := run.Results[0]
+	c.Assert(result.Output.StdOut, Equals, "paid ]]> done\nlogged out")
+	c.Assert(result.Output.ErrorInfo, IsNil)
+	c.Assert(result.ResultFiles, DeepEquals, []TrxResultFile{{Path: "paid.png"}})
+	c.Assert(builder.Attachments(), DeepEquals, []Attachment{{File: "paid.png", Path: "trx/In/" + result.RelativeResultsDirectory + "/paid.png"}})
+}
+
+func (s *MySuite) TestTrxOfPassingSuiteIsCompleted(c *C) {
+	report := &model.Report{Specs: []*model.Spec{{Name: "Payment", Scenarios: []*model.Scenario{{Name: "Pay", Heading: "Pay"}}}}}
+
+	run := encodeTrx(c, Config{}, report)
+
+	c.Assert(run.ResultSummary.Outcome, Equals, "Completed")
+	c.Assert(run.Results[0].Output, IsNil)
+}
+
+func (s *MySuite) TestTrxSanitizesAndTruncatesFields(c *C) {
+	run := encodeTrx(c, Config{MaxFieldSize: truncatedFieldSize}, newTruncatedReport())
+
+	c.Assert(run.Results[0].TestName, Equals, "Pay")
+	c.Assert(run.TestDefinitions[0].Name, Equals, "Pay")
+	c.Assert(*run.Results[0].Output.ErrorInfo, Equals, TrxErrorInfo{Message: truncatedMessage, StackTrace: truncatedStackTrace})
+}
+
+func (s *MySuite) TestTrxDeterministicReportsAreIndependentOfExecution(c *C) {
+	bytes := encodeReorderedReports(c, TrxFormat)
+
+	var run TrxTestRun
+	c.Assert(xml.Unmarshal(bytes, &run), Equals, nil)
+
+	c.Assert(run.Times.Start, Equals, "1970-01-01T00:00:00.0000000Z")
+	c.Assert(run.Results[0].TestName, Equals, "A1")
+	c.Assert(run.Results[1].TestName, Equals, "B1")
+	c.Assert(run.Results[1].ComputerName, Equals, "localhost")
+	c.Assert(run.Results[1].Duration, Equals, "00:00:00.0000000")
+}
@@ -7,29 +7,42 @@
 package builder
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"encoding/xml"
 	"fmt"
 	"os"
+	"path"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
-	"path/filepath"
-
 	"github.com/getgauge/gauge-proto/go/gauge_messages"
+	"github.com/getgauge/xml-report/model"
 )
 
 const (
-	hostname            = "HOSTNAME"
-	timeStampFormat     = "%d-%02d-%02dT%02d:%02d:%02d"
-	preHookFailureMsg   = "Pre Hook Failure"
-	postHookFailureMsg  = "Post Hook Failure"
-	executionFailureMsg = "Execution Failure"
+	hostname           = "HOSTNAME"
+	suiteHooksName     = "Suite Hooks"
+	validationErrorMsg = "Validation Errors"
+	retryType          = "Retry"
+	// AttachmentsDir is the directory, relative to the report directory, holding screenshots.
+	AttachmentsDir = "attachments"
 )
 
-// JUnitTestSuites is a collection of JUnit test suites.
+// JUnitTestSuites is a collection of JUnit test suites, with the totals
+// of all its suites.
 type JUnitTestSuites struct {
-	XMLName xml.Name         `xml:"testsuites"`
-	Suites  []JUnitTestSuite `xml:"testsuite"`
+	XMLName   xml.Name         `xml:"testsuites"`
+	Name      string           `xml:"name,attr,omitempty"`
+	Tests     int              `xml:"tests,attr"`
+	Failures  int              `xml:"failures,attr"`
+	Errors    int              `xml:"errors,attr"`
+	Skipped   int              `xml:"skipped,attr"`
+	Time      string           `xml:"time,attr"`
+	Timestamp string           `xml:"timestamp,attr"`
+	Suites    []JUnitTestSuite `xml:"testsuite"`
 }
 
 // JUnitTestSuite is a single JUnit test suite which may contain many
@@ -54,22 +67,33 @@ type JUnitTestSuite struct {
 
 // JUnitTestCase is a single test case with its result.
 type JUnitTestCase struct {
-	XMLName     xml.Name          `xml:"testcase"`
-	Classname   string            `xml:"classname,attr"`
-	Name        string            `xml:"name,attr"`
-	Time        string            `xml:"time,attr"`
-	SkipMessage *JUnitSkipMessage `xml:"skipped,omitempty"`
-	Failure     *JUnitFailure     `xml:"failure,omitempty"`
+	XMLName      xml.Name          `xml:"testcase"`
+	Classname    string            `xml:"classname,attr"`
+	Name         string            `xml:"name,attr"`
+	Time         string            `xml:"time,attr"`
+	File         string            `xml:"file,attr,omitempty"`
+	Line         int64             `xml:"line,attr,omitempty"`
+	Properties   []JUnitProperty   `xml:"properties>property,omitempty"`
+	SkipMessage  *JUnitSkipMessage `xml:"skipped,omitempty"`
+	Error        *JUnitError       `xml:"error,omitempty"`
+	Failure      *JUnitFailure     `xml:"failure,omitempty"`
+	Flaky        []JUnitRerun      `xml:"flakyFailure,omitempty"`
+	Reruns       []JUnitRerun      `xml:"rerunFailure,omitempty"`
+	RerunErrors  []JUnitRerun      `xml:"rerunError,omitempty"`
+	SystemOutput *SystemOut
+	SystemError  *SystemErr
 }
 
 type SystemOut struct {
 	XMLName  xml.Name `xml:"system-out"`
 	Contents string   `xml:",chardata"`
+	cdata    bool
 }
 
 type SystemErr struct {
 	XMLName  xml.Name `xml:"system-err"`
 	Contents string   `xml:",chardata"`
+	cdata    bool
 }
 
 // JUnitSkipMessage contains the reason why a testcase was skipped.
@@ -88,260 +112,489 @@ type JUnitFailure struct {
 	Message  string `xml:"message,attr"`
 	Type     string `xml:"type,attr"`
 	Contents string `xml:",chardata"`
+	cdata    bool
 }
 
-type XmlBuilder struct {
-	currentId int
-	suites    JUnitTestSuites
+// JUnitError contains data related to a test which had an unanticipated
+// problem, e.g. a hook failure or a non-assertion exception.
+type JUnitError struct {
+	Message  string `xml:"message,attr"`
+	Type     string `xml:"type,attr"`
+	Contents string `xml:",chardata"`
+	cdata    bool
+}
+
+// JUnitRerun marks a retried test, reported using the Maven Surefire
+// flakyFailure, rerunFailure and rerunError elements. It has no stackTrace,
+// as Gauge does not keep the failures of the earlier runs.
+type JUnitRerun struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+}
+
+// Config holds the report settings that are not part of the execution result.
+type Config struct {
+	// Properties are added to the properties of every testsuite, e.g. tool
+	// versions and selected environment variables.
+	Properties []JUnitProperty
+	// ProjectRoot is used to report spec files relative to the project.
+	ProjectRoot string
+	// ReportDir is the directory the report and its attachments are written
+	// to, for formats which refer to attachments by absolute path.
+	ReportDir string
+	// Clock is used when the execution result carries no timestamps.
+	// Defaults to time.Now.
+	Clock func() time.Time
+	// RetryFormat is one of RetryFormatSurefire or RetryFormatNone.
+	RetryFormat string
+	// ScenarioNameTemplate and TableScenarioNameTemplate name the testcases of
+	// scenarios and table driven scenarios, see model/naming.go for placeholders.
+	ScenarioNameTemplate      string
+	TableScenarioNameTemplate string
+	// StableNames leaves table values out of the default table driven names.
+	StableNames bool
+	// ClassnameStrategy is one of ClassnameHeading or ClassnameDirectory.
+	ClassnameStrategy string
+	// CDataThreshold is the size in bytes above which failure contents and
+	// system-out/err are written as CDATA. Zero disables CDATA.
+	CDataThreshold int
+	// MaxFieldSize is the number of bytes kept of each message, stack trace
+	// and output section. MaxFileSize is the size in bytes the report is
+	// truncated to fit. Zero disables the limit.
+	MaxFieldSize int
+	MaxFileSize  int
+	// Deterministic sorts the report and replaces hostnames, timestamps and
+	// durations with fixed values, see deterministic.go.
+	Deterministic bool
+}
+
+// ModelOptions returns the settings the report model is built with.
+func (c Config) ModelOptions() model.Options {
+	return model.Options{
+		ProjectRoot:               c.ProjectRoot,
+		Clock:                     c.Clock,
+		ScenarioNameTemplate:      c.ScenarioNameTemplate,
+		TableScenarioNameTemplate: c.TableScenarioNameTemplate,
+		StableNames:               c.StableNames,
+	}
 }
 
-func NewXmlBuilder(id int) *XmlBuilder {
-	return &XmlBuilder{currentId: id}
+const (
+	// RetryFormatSurefire reports retried scenarios with Surefire's
+	// flakyFailure/rerunFailure elements. This is the default.
+	RetryFormatSurefire = "surefire"
+	// RetryFormatNone reports only the outcome of the last run.
+	RetryFormatNone = "none"
+)
+
+const (
+	// ClassnameHeading uses the spec heading as testcase classname. This is the default.
+	ClassnameHeading = "heading"
+	// ClassnameDirectory prefixes the spec heading with a dotted package
+	// derived from the spec's directory, e.g. specs.checkout.payment.Card_payment.
+	ClassnameDirectory = "directory"
+)
+
+var nonIdentifierChars = regexp.MustCompile(`[^\p{L}\p{N}_$-]`)
+
+// XmlBuilder writes the JUnit XML report of a suite.
+type XmlBuilder struct {
+	currentId       int
+	config          Config
+	suites          JUnitTestSuites
+	suiteProperties []JUnitProperty
+	attachments     []Attachment
 }
 
-type StepFailure struct {
-	Message string
-	Err     string
+func NewXmlBuilder(id int, config Config) *XmlBuilder {
+	return &XmlBuilder{currentId: id, config: config}
 }
 
 func (x *XmlBuilder) GetXmlContent(executionSuiteResult *gauge_messages.SuiteExecutionResult) ([]byte, error) {
-	suiteResult := executionSuiteResult.GetSuiteResult()
+	return x.Encode(model.NewReport(executionSuiteResult, x.config.ModelOptions()))
+}
+
+// Encode returns the JUnit XML report of the suite.
+func (x *XmlBuilder) Encode(report *model.Report) ([]byte, error) {
 	x.suites = JUnitTestSuites{}
-	for _, result := range suiteResult.GetSpecResults() {
-		x.getSpecContent(result)
+	x.attachments = nil
+	x.suiteProperties = getReportProperties(report, x.config)
+	for _, spec := range report.Specs {
+		x.getSpecContent(spec)
 	}
-	bytes, err := xml.MarshalIndent(x.suites, "", "\t")
+	x.getSuiteHookContent(report)
+	x.setTotals(report)
+	if x.config.Deterministic {
+		x.makeDeterministic()
+	}
+	x.sanitize()
+	x.truncateFields()
+	bytes, err := x.marshal()
 	if err != nil {
 		return nil, err
 	}
 	return bytes, nil
 }
 
-func (x *XmlBuilder) getSpecContent(result *gauge_messages.ProtoSpecResult) {
+func (x *XmlBuilder) getSpecContent(spec *model.Spec) {
 	x.currentId += 1
-	hostName, err := os.Hostname()
-	if err != nil {
-		hostName = hostname
-	}
-	ts := x.getTestSuite(result, hostName)
-	if hasParseErrors(result.Errors) {
-		ts.Failures++
-		ts.TestCases = append(ts.TestCases, getErrorTestCase(result))
+	ts := x.getTestSuite(spec, getHostName())
+	if spec.HasParseErrors() {
+		ts.Errors++
+		ts.TestCases = append(ts.TestCases, x.getErrorTestCase(spec))
 	} else {
-		s := result.GetProtoSpec()
-		ts.Failures += len(s.GetPreHookFailures()) + len(s.GetPostHookFailures())
-		for _, test := range result.GetProtoSpec().GetItems() {
-			if test.GetItemType() == gauge_messages.ProtoItem_Scenario {
-				x.getScenarioContent(result, test.GetScenario(), &ts)
-			} else if test.GetItemType() == gauge_messages.ProtoItem_TableDrivenScenario {
-				x.getTableDrivenScenarioContent(result, test.GetTableDrivenScenario(), &ts)
-			}
+		x.getSpecValidationContent(spec, &ts)
+		x.getSpecHookContent(spec, spec.BeforeHooks, &ts)
+		for _, scenario := range spec.Scenarios {
+			x.getScenarioContent(spec, scenario, &ts)
 		}
+		x.getSpecHookContent(spec, spec.AfterHooks, &ts)
 	}
 	x.suites.Suites = append(x.suites.Suites, ts)
 }
-func getErrorTestCase(result *gauge_messages.ProtoSpecResult) JUnitTestCase {
-	var failures []string
-	for _, e := range result.Errors {
-		t := "Parse"
-		if e.Type == gauge_messages.Error_VALIDATION_ERROR {
-			t = "Validation"
+
+// setTotals sets the aggregated counts of all testsuites on the root element.
+func (x *XmlBuilder) setTotals(report *model.Report) {
+	x.suites.Name = report.ProjectName
+	x.suites.Time = formatTime(report.Duration)
+	x.suites.Timestamp = formatTimestamp(report.StartTime)
+	for _, ts := range x.suites.Suites {
+		x.suites.Tests += ts.Tests
+		x.suites.Failures += ts.Failures
+		x.suites.Errors += ts.Errors
+		x.suites.Skipped += ts.SkippedTestCount
+	}
+}
+
+// getSuiteHookContent adds a synthetic testsuite holding a testcase for each
+// failed BeforeSuite/AfterSuite hook and the messages of the suite hooks in
+// its system-out. Nothing is added if the hooks passed without messages.
+func (x *XmlBuilder) getSuiteHookContent(report *model.Report) {
+	var testCases []JUnitTestCase
+	for _, hook := range []*model.Hook{report.BeforeHook, report.AfterHook} {
+		if hook != nil {
+			testCases = append(testCases, x.getHookTestCase(suiteHooksName, hook.Name, hook))
 		}
-		failures = append(failures, fmt.Sprintf("[%s Error] %s", t, e.Message))
 	}
+	if len(testCases) == 0 && len(report.Output) == 0 {
+		return
+	}
+	x.currentId += 1
+	x.suites.Suites = append(x.suites.Suites, JUnitTestSuite{
+		Id:           x.currentId,
+		Tests:        len(testCases),
+		Errors:       len(testCases),
+		Time:         formatTime(0),
+		Timestamp:    formatTimestamp(report.StartTime),
+		Name:         suiteHooksName,
+		Hostname:     getHostName(),
+		Properties:   x.suiteProperties,
+		TestCases:    testCases,
+		SystemOutput: SystemOut{Contents: strings.Join(report.Output, "\n")},
+	})
+}
+
+// getSpecHookContent adds an errored testcase for each failed spec hook. For
+// data driven specs the name carries the table row the hook failed for.
+func (x *XmlBuilder) getSpecHookContent(spec *model.Spec, hooks []*model.Hook, ts *JUnitTestSuite) {
+	for _, hook := range hooks {
+		ts.Tests++
+		ts.Errors++
+		testCase := x.getHookTestCase(getSpecClassname(spec, x.config.ClassnameStrategy), hook.Name, hook)
+		testCase.File = spec.File
+		ts.TestCases = append(ts.TestCases, testCase)
+	}
+}
+
+// getHookTestCase reports a hook failure as an errored testcase, with the
+// failure screenshot attached to its system-out.
+func (x *XmlBuilder) getHookTestCase(classname, name string, hook *model.Hook) JUnitTestCase {
+	message := hook.Failure.Message
+	testCase := JUnitTestCase{
+		Classname: classname,
+		Name:      name,
+		Time:      formatTime(0),
+		Error:     &JUnitError{Message: message, Type: message, Contents: hook.Failure.StackTrace},
+	}
+	if attachments := x.getAttachments(hook.Screenshot); len(attachments) > 0 {
+		testCase.SystemOutput = &SystemOut{Contents: strings.Join(attachments, "\n")}
+	}
+	return testCase
+}
+
+func (x *XmlBuilder) getErrorTestCase(spec *model.Spec) JUnitTestCase {
 	return JUnitTestCase{
-		Classname: getSpecName(result.GetProtoSpec()),
-		Name:      getSpecName(result.GetProtoSpec()),
-		Time:      formatTime(int(result.GetExecutionTime())),
-		Failure: &JUnitFailure{
+		Classname: getSpecClassname(spec, x.config.ClassnameStrategy),
+		Name:      spec.Name,
+		Time:      formatTime(spec.Duration),
+		Error: &JUnitError{
 			Message:  "Parse/Validation Errors",
 			Type:     "Parse/Validation Errors",
-			Contents: strings.Join(failures, "\n"),
+			Contents: joinErrors(spec.Errors),
 		},
 	}
 }
 
-func (x *XmlBuilder) getScenarioContent(result *gauge_messages.ProtoSpecResult, scenario *gauge_messages.ProtoScenario, ts *JUnitTestSuite) {
+// getSpecValidationContent adds an errored testcase for the validation errors
+// which are not within any scenario, e.g. in the spec's context steps or in
+// concept files. Errors within a scenario are reported on the scenario's
+// testcase. The testcase points at the first error, in whichever file it is.
+func (x *XmlBuilder) getSpecValidationContent(spec *model.Spec, ts *JUnitTestSuite) {
+	if len(spec.ValidationErrors) == 0 {
+		return
+	}
+	first := spec.ValidationErrors[0]
+	file := spec.File
+	if first.File != "" {
+		file = first.File
+	}
+	ts.Tests++
+	ts.Errors++
+	ts.TestCases = append(ts.TestCases, JUnitTestCase{
+		Classname: getSpecClassname(spec, x.config.ClassnameStrategy),
+		Name:      spec.Name,
+		Time:      formatTime(0),
+		File:      file,
+		Line:      int64(first.Line),
+		Error:     getValidationError(spec.ValidationErrors),
+	})
+}
+
+func getValidationError(errors []model.Error) *JUnitError {
+	return &JUnitError{Message: validationErrorMsg, Type: validationErrorMsg, Contents: joinErrors(errors)}
+}
+
+// joinErrors formats the errors one per line.
+func joinErrors(errors []model.Error) string {
+	var messages []string
+	for _, e := range errors {
+		messages = append(messages, e.String())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// getScenarioContent adds a testcase for the scenario.
+func (x *XmlBuilder) getScenarioContent(spec *model.Spec, scenario *model.Scenario, ts *JUnitTestSuite) {
 	testCase := JUnitTestCase{
-		Classname: getSpecName(result.GetProtoSpec()),
-		Name:      scenario.GetScenarioHeading(),
-		Time:      formatTime(int(scenario.GetExecutionTime())),
-		Failure:   nil,
-	}
-	if scenario.GetExecutionStatus() == gauge_messages.ExecutionStatus_FAILED {
-		var errors []string
-		failures := x.getFailure(scenario)
-		message := "Multiple failures"
-		for _, step := range failures {
-			errors = append(errors, fmt.Sprintf("%s\n%s", step.Message, step.Err))
-		}
-		if len(failures) == 1 {
-			message = failures[0].Message
-			errors = []string{failures[0].Err}
+		Classname:  getSpecClassname(spec, x.config.ClassnameStrategy),
+		Name:       scenario.Name,
+		Time:       formatTime(scenario.Duration),
+		File:       spec.File,
+		Line:       scenario.Line,
+		Properties: append(appendProperty(nil, "scenario.tags", strings.Join(scenario.Tags, ",")), getScenarioProperties(scenario)...),
+	}
+	if scenario.Status == model.Failed {
+		message, contents := scenario.Failures.Summary()
+		if scenario.Errored() {
+			// The scenario is already counted in the spec's failed count.
+			ts.Failures--
+			ts.Errors++
+			testCase.Error = &JUnitError{Message: message, Type: message, Contents: contents}
+		} else {
+			testCase.Failure = &JUnitFailure{Message: message, Type: message, Contents: contents}
 		}
-		testCase.Failure = &JUnitFailure{
-			Message:  message,
-			Type:     message,
-			Contents: strings.Join(errors, "\n\n"),
+		testCase.SystemError = &SystemErr{Contents: scenario.Failures.Text()}
+	} else if len(scenario.Errors) > 0 {
+		// The scenario is counted as skipped by Gauge.
+		if scenario.Status == model.Skipped {
+			ts.SkippedTestCount--
 		}
-	} else if scenario.GetExecutionStatus() == gauge_messages.ExecutionStatus_SKIPPED {
+		ts.Errors++
+		testCase.Error = getValidationError(scenario.Errors)
+	} else if scenario.Status == model.Skipped {
 		testCase.SkipMessage = &JUnitSkipMessage{
-			Message: strings.Join(scenario.SkipErrors, "\n"),
+			Message: strings.Join(scenario.SkipReasons, "\n"),
 		}
 	}
+	if x.config.RetryFormat != RetryFormatNone {
+		setRetries(&testCase, scenario.Retries)
+	}
+	if output := x.getOutput(scenario.Output); len(output) > 0 {
+		testCase.SystemOutput = &SystemOut{Contents: strings.Join(output, "\n")}
+	}
 	ts.TestCases = append(ts.TestCases, testCase)
 }
 
-func (x *XmlBuilder) getTableDrivenScenarioContent(result *gauge_messages.ProtoSpecResult, tableDriven *gauge_messages.ProtoTableDrivenScenario, ts *JUnitTestSuite) {
-	scenario := tableDriven.GetScenario()
-	if scenario == nil {
+// setRetries marks a retried scenario with a single flakyFailure, or a
+// rerunFailure/rerunError if it kept failing, stating the number of runs.
+// Gauge only keeps the result of the last run, so the earlier failures are
+// not reported.
+func setRetries(testCase *JUnitTestCase, retries int) {
+	if retries <= 0 {
 		return
 	}
-	var tableValues strings.Builder
-	if tableDriven.IsSpecTableDriven {
-		specTable := findSpecTable(result) // SpecTable not included in TableDrivenScenario msg; find it in the spec.
-		rowIndex := tableDriven.GetTableRowIndex()
-		var specTableValues = buildHeaderValuesFromTable(specTable, int(rowIndex))
-		fmt.Fprintf(&tableValues, " SpecRow: %d: %s", rowIndex+1, strings.Join(specTableValues, " "))
+	runs := retries + 1
+	switch {
+	case testCase.Failure != nil:
+		testCase.Reruns = []JUnitRerun{{Message: fmt.Sprintf("Failed all %d runs", runs), Type: retryType}}
+	case testCase.Error != nil:
+		testCase.RerunErrors = []JUnitRerun{{Message: fmt.Sprintf("Failed all %d runs", runs), Type: retryType}}
+	case testCase.SkipMessage == nil:
+		testCase.Flaky = []JUnitRerun{{Message: fmt.Sprintf("Failed %d of %d runs", retries, runs), Type: retryType}}
 	}
-	if tableDriven.IsScenarioTableDriven {
-		rowIndex := tableDriven.GetScenarioTableRowIndex()
-		var scenarioTableValues = buildHeaderValuesFromTable(tableDriven.ScenarioDataTable, int(rowIndex))
-		fmt.Fprintf(&tableValues, " ScnRow: %d: %s", rowIndex+1, strings.Join(scenarioTableValues, " "))
+}
+
+// getOutput returns the lines of system-out, with screenshots referenced as
+// attachment lines next to the messages.
+func (x *XmlBuilder) getOutput(output []model.Output) []string {
+	var lines []string
+	for _, o := range output {
+		if o.Screenshot != "" {
+			lines = append(lines, x.getAttachments(o.Screenshot)...)
+		} else {
+			lines = append(lines, o.Message)
+		}
 	}
+	return lines
+}
 
-	scenario.ScenarioHeading += " |" + tableValues.String()
-	x.getScenarioContent(result, scenario, ts)
+// getAttachments records the given screenshot files and returns the
+// "[[ATTACHMENT|path]]" lines understood by the Jenkins JUnit Attachments
+// plugin and GitLab.
+func (x *XmlBuilder) getAttachments(files ...string) []string {
+	return getAttachmentLines(&x.attachments, files...)
 }
 
-// Find spec table as the first ProtoTable in the spec items (there is at most one per spec).
-func findSpecTable(result *gauge_messages.ProtoSpecResult) *gauge_messages.ProtoTable {
-	if result == nil || result.GetProtoSpec() == nil {
-		return nil
-	}
-	for _, item := range result.GetProtoSpec().GetItems() {
-		if item.GetItemType() == gauge_messages.ProtoItem_Table {
-			return item.GetTable()
+// getAttachmentLines appends the given screenshot files to attachments and
+// returns an "[[ATTACHMENT|path]]" line for each of them. Paths are relative
+// to the report directory.
+func getAttachmentLines(attachments *[]Attachment, files ...string) []string {
+	var lines []string
+	for _, file := range files {
+		if file == "" {
+			continue
 		}
+		*attachments = append(*attachments, Attachment{File: file, Path: AttachmentPath(file)})
+		lines = append(lines, fmt.Sprintf("[[ATTACHMENT|%s]]", AttachmentPath(file)))
 	}
-	return nil
+	return lines
 }
 
-// Builds "[Header: Value]" pairs for the given table and rowIndex.
-func buildHeaderValuesFromTable(table *gauge_messages.ProtoTable, rowIndex int) []string {
-	var headerValues []string
-	if table == nil {
-		return headerValues
+// AttachmentPath returns the path, relative to the report directory, at
+// which the given screenshot file is expected to be copied. Files outside the
+// screenshots directory are prefixed with a hash of their directory, so that
+// screenshots with the same name in different directories do not overwrite
+// each other.
+func AttachmentPath(file string) string {
+	name := filepath.Base(file)
+	if dir := filepath.Dir(file); dir != "." {
+		sum := sha1.Sum([]byte(filepath.ToSlash(dir)))
+		name = hex.EncodeToString(sum[:4]) + "-" + name
 	}
-	headersRow := table.GetHeaders()
-	rows := table.GetRows()
-	if headersRow == nil || len(headersRow.GetCells()) == 0 || len(rows) == 0 {
-		return headerValues
-	}
-	if rowIndex < 0 || rowIndex >= len(rows) {
-		return headerValues
+	return path.Join(AttachmentsDir, name)
+}
+
+// Attachments returns the screenshot files referenced by the last generated report.
+func (x *XmlBuilder) Attachments() []Attachment {
+	return x.attachments
+}
+
+// getScenarioProperties returns the ID and retry count of the scenario, and
+// the values of the data table rows of a table driven scenario. Formats
+// report the tags in their own way.
+func getScenarioProperties(scenario *model.Scenario) []JUnitProperty {
+	var properties []JUnitProperty
+	properties = appendProperty(properties, "scenario.id", scenario.ID)
+	if scenario.Retries > 0 {
+		properties = appendProperty(properties, "scenario.retries", fmt.Sprint(scenario.Retries))
 	}
-	headers := headersRow.GetCells()
-	rowData := rows[rowIndex].GetCells()
+	properties = append(properties, buildRowProperties("specRow.", scenario.SpecRow)...)
+	return append(properties, buildRowProperties("scnRow.", scenario.ScenarioRow)...)
+}
 
-	for i := 0; i < len(headers); i++ {
-		headerValues = append(headerValues, fmt.Sprintf("[%s: %s]", headers[i], rowData[i]))
+// Builds "<prefix><Header>" properties holding the values of the given table row.
+func buildRowProperties(prefix string, row *model.TableRow) []JUnitProperty {
+	var properties []JUnitProperty
+	if row == nil {
+		return properties
 	}
-	return headerValues
+	for i, header := range row.Headers {
+		if i < len(row.Cells) {
+			properties = append(properties, JUnitProperty{Name: prefix + header, Value: row.Cells[i]})
+		}
+	}
+	return properties
 }
 
-func (x *XmlBuilder) getTestSuite(result *gauge_messages.ProtoSpecResult, hostName string) JUnitTestSuite {
-	now := time.Now()
-	formattedNow := fmt.Sprintf(timeStampFormat, now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), now.Second())
+func (x *XmlBuilder) getTestSuite(spec *model.Spec, hostName string) JUnitTestSuite {
 	systemError := SystemErr{}
-	if result.GetScenarioSkippedCount() > 0 {
-		systemError.Contents = fmt.Sprintf("Validation failed, %d Scenarios were skipped.", result.GetScenarioSkippedCount())
+	if spec.SkippedCount > 0 {
+		systemError.Contents = fmt.Sprintf("Validation failed, %d Scenarios were skipped.", spec.SkippedCount)
+	}
+	pkg := spec.FileName
+	if x.config.Deterministic {
+		pkg = spec.File
 	}
 	return JUnitTestSuite{
 		Id:               int(x.currentId),
-		Tests:            int(result.GetScenarioCount()),
-		Failures:         int(result.GetScenarioFailedCount()),
-		Time:             formatTime(int(result.GetExecutionTime())),
-		Timestamp:        formattedNow,
-		Name:             getSpecName(result.GetProtoSpec()),
+		Tests:            spec.ScenarioCount,
+		Failures:         spec.FailedCount,
+		Time:             formatTime(spec.Duration),
+		Timestamp:        formatTimestamp(spec.StartTime),
+		Name:             spec.Name,
 		Errors:           0,
 		Hostname:         hostName,
-		Package:          result.GetProtoSpec().GetFileName(),
-		Properties:       []JUnitProperty{},
+		Package:          pkg,
+		Properties:       x.getSpecProperties(spec),
 		TestCases:        []JUnitTestCase{},
-		SkippedTestCount: int(result.GetScenarioSkippedCount()),
-		SystemOutput:     SystemOut{},
+		SkippedTestCount: spec.SkippedCount,
+		SystemOutput:     SystemOut{Contents: strings.Join(spec.Output, "\n")},
 		SystemError:      systemError,
 	}
 }
 
-func (x *XmlBuilder) getFailure(test *gauge_messages.ProtoScenario) []StepFailure {
-	errInfo := []StepFailure{}
-	hookInfo := x.getFailureFromExecutionResult(test.GetScenarioHeading(), test.GetPreHookFailure(), test.GetPostHookFailure(), nil, "Scenario ")
-	if hookInfo.Message != "" {
-		return append(errInfo, hookInfo)
-	}
-	contextsInfo := x.getFailureFromSteps(test.GetContexts(), "Step ")
-	if len(contextsInfo) > 0 {
-		errInfo = append(errInfo, contextsInfo...)
-	}
-	stepsInfo := x.getFailureFromSteps(test.GetScenarioItems(), "Step ")
-	if len(stepsInfo) > 0 {
-		errInfo = append(errInfo, stepsInfo...)
-	}
-	return errInfo
+// getReportProperties returns the project, environment and tags of the run
+// followed by the configured properties.
+func getReportProperties(report *model.Report, config Config) []JUnitProperty {
+	properties := []JUnitProperty{}
+	properties = appendProperty(properties, "gauge.project", report.ProjectName)
+	properties = appendProperty(properties, "gauge.environment", report.Environment)
+	properties = appendProperty(properties, "gauge.tags", report.Tags)
+	return append(properties, config.Properties...)
 }
 
-func (x *XmlBuilder) getFailureFromSteps(items []*gauge_messages.ProtoItem, prefix string) []StepFailure {
-	errInfo := []StepFailure{}
-	for _, item := range items {
-		stepInfo := StepFailure{Message: "", Err: ""}
-		if item.GetItemType() == gauge_messages.ProtoItem_Step {
-			preHookFailure := item.GetStep().GetStepExecutionResult().GetPreHookFailure()
-			postHookFailure := item.GetStep().GetStepExecutionResult().GetPostHookFailure()
-			result := item.GetStep().GetStepExecutionResult().GetExecutionResult()
-			stepInfo = x.getFailureFromExecutionResult(item.GetStep().GetActualText(), preHookFailure, postHookFailure, result, prefix)
-		} else if item.GetItemType() == gauge_messages.ProtoItem_Concept {
-			errInfo = append(errInfo, x.getFailureFromSteps(item.GetConcept().GetSteps(), "Concept ")...)
-		}
-		if stepInfo.Message != "" {
-			errInfo = append(errInfo, stepInfo)
-		}
+func (x *XmlBuilder) getSpecProperties(spec *model.Spec) []JUnitProperty {
+	properties := appendProperty([]JUnitProperty{}, "spec.tags", strings.Join(spec.Tags, ","))
+	return append(properties, x.suiteProperties...)
+}
+
+// appendProperty adds the property unless its value is empty.
+func appendProperty(properties []JUnitProperty, name, value string) []JUnitProperty {
+	if value == "" {
+		return properties
 	}
-	return errInfo
+	return append(properties, JUnitProperty{Name: name, Value: value})
 }
 
-func (x *XmlBuilder) getFailureFromExecutionResult(name string, preHookFailure *gauge_messages.ProtoHookFailure,
-	postHookFailure *gauge_messages.ProtoHookFailure, stepExecutionResult *gauge_messages.ProtoExecutionResult, prefix string) StepFailure {
-	if len(name) > 0 {
-		name = fmt.Sprintf("%s\n", name)
+// getSpecClassname names the spec using one of ClassnameHeading or
+// ClassnameDirectory.
+func getSpecClassname(spec *model.Spec, strategy string) string {
+	if strategy != ClassnameDirectory {
+		return spec.Name
+	}
+	var parts []string
+	if dir := path.Dir(spec.File); dir != "." && dir != "/" {
+		parts = strings.Split(strings.Trim(dir, "/"), "/")
 	}
-	if preHookFailure != nil {
-		return StepFailure{Message: fmt.Sprintf("%s%s%s: '%s'", name, prefix, preHookFailureMsg, preHookFailure.GetErrorMessage()), Err: preHookFailure.GetStackTrace()}
-	} else if postHookFailure != nil {
-		return StepFailure{Message: fmt.Sprintf("%s%s%s: '%s'", name, prefix, postHookFailureMsg, postHookFailure.GetErrorMessage()), Err: postHookFailure.GetStackTrace()}
-	} else if stepExecutionResult != nil && stepExecutionResult.GetFailed() {
-		return StepFailure{Message: fmt.Sprintf("%s%s%s: '%s'", name, prefix, executionFailureMsg, stepExecutionResult.GetErrorMessage()), Err: stepExecutionResult.GetStackTrace()}
+	parts = append(parts, spec.Name)
+	for i, part := range parts {
+		parts[i] = nonIdentifierChars.ReplaceAllString(strings.TrimSpace(part), "_")
 	}
-	return StepFailure{"", ""}
+	return strings.Join(parts, ".")
 }
 
-func getSpecName(spec *gauge_messages.ProtoSpec) string {
-	if strings.TrimSpace(spec.SpecHeading) == "" {
-		return filepath.Base(spec.GetFileName())
+func getHostName() string {
+	hostName, err := os.Hostname()
+	if err != nil {
+		return hostname
 	}
-	return spec.SpecHeading
+	return hostName
 }
 
-func hasParseErrors(errors []*gauge_messages.Error) bool {
-	for _, e := range errors {
-		if e.Type == gauge_messages.Error_PARSE_ERROR {
-			return true
-		}
-	}
-	return false
+func formatTimestamp(t time.Time) string {
+	return t.Format(time.RFC3339)
 }
 
-func formatTime(time int) string {
-	return fmt.Sprintf("%.3f", float64(time)/1000.0)
+func formatTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
 }
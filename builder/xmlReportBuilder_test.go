@@ -7,11 +7,11 @@
 package builder
 
 import (
+	"strings"
 	"testing"
+	"time"
 
-	"path/filepath"
-
-	"github.com/getgauge/gauge-proto/go/gauge_messages"
+	"github.com/getgauge/xml-report/model"
 	. "gopkg.in/check.v1"
 )
 
@@ -23,82 +23,65 @@ type MySuite struct{}
 
 var _ = Suite(&MySuite{})
 
-func (s *MySuite) TestGetSpecNameWhenHeadingIsPresent(c *C) {
-	want := "heading"
+func (s *MySuite) TestGetSpecClassnameFromHeading(c *C) {
+	spec := &model.Spec{Name: "Card payment", File: "specs/checkout/card.spec"}
 
-	got := getSpecName(&gauge_messages.ProtoSpec{SpecHeading: "heading"})
+	got := getSpecClassname(spec, ClassnameHeading)
 
-	c.Assert(want, Equals, got)
+	c.Assert(got, Equals, "Card payment")
 }
 
-func (s *MySuite) TestGetSpecNameWhenHeadingIsNotPresent(c *C) {
-	want := "example.spec"
+func (s *MySuite) TestGetSpecClassnameFromDirectory(c *C) {
+	spec := &model.Spec{Name: "Card payment", File: "specs/checkout/payment/card.spec"}
 
-	got := getSpecName(&gauge_messages.ProtoSpec{FileName: filepath.Join("specs", "specs1", "example.spec")})
+	got := getSpecClassname(spec, ClassnameDirectory)
 
-	c.Assert(want, Equals, got)
+	c.Assert(got, Equals, "specs.checkout.payment.Card_payment")
 }
 
-func (s *MySuite) TestHasParseErrors(c *C) {
-	errors := []*gauge_messages.Error{
-		{Type: gauge_messages.Error_PARSE_ERROR},
-		{Type: gauge_messages.Error_VALIDATION_ERROR},
-	}
+func (s *MySuite) TestGetSpecClassnameFromDirectoryWithoutHeading(c *C) {
+	spec := &model.Spec{Name: "my.first.spec", File: "specs/my.first.spec"}
 
-	got := hasParseErrors(errors)
+	got := getSpecClassname(spec, ClassnameDirectory)
 
-	c.Assert(true, Equals, got)
-}
-
-func (s *MySuite) TestHasParseErrorsWithNoErrors(c *C) {
-	errors := []*gauge_messages.Error{}
-
-	got := hasParseErrors(errors)
-
-	c.Assert(false, Equals, got)
-}
-
-func (s *MySuite) TestHasParseErrorsWithOnyValidationErrors(c *C) {
-	errors := []*gauge_messages.Error{
-		{Type: gauge_messages.Error_VALIDATION_ERROR},
-		{Type: gauge_messages.Error_VALIDATION_ERROR},
-	}
-
-	got := hasParseErrors(errors)
-
-	c.Assert(false, Equals, got)
+	c.Assert(got, Equals, "specs.my_first_spec")
 }
 
 func (s *MySuite) TestGetErrorTestCase(c *C) {
-	res := &gauge_messages.ProtoSpecResult{
-		ProtoSpec: &gauge_messages.ProtoSpec{
-			SpecHeading: "heading",
+	spec := &model.Spec{
+		Name: "heading",
+		Errors: []model.Error{
+			{Type: model.ParseError, Message: "parse error"},
+			{Type: model.ValidationError, Message: "validation error"},
 		},
-		Errors: []*gauge_messages.Error{
-			{
-				Type:    gauge_messages.Error_PARSE_ERROR,
-				Message: "parse error",
-			},
-			{
-				Type:    gauge_messages.Error_VALIDATION_ERROR,
-				Message: "validation error",
-			},
-		},
-		ExecutionTime: int64(1),
+		Duration: time.Millisecond,
 	}
 
 	want := JUnitTestCase{
 		Classname: "heading",
 		Name:      "heading",
-		Time:      formatTime(int(1)),
-		Failure: &JUnitFailure{
+		Time:      formatTime(time.Millisecond),
+		Error: &JUnitError{
 			Message:  "Parse/Validation Errors",
 			Type:     "Parse/Validation Errors",
 			Contents: "[Parse Error] parse error\n[Validation Error] validation error",
 		},
 	}
 
-	got := getErrorTestCase(res)
+	got := NewXmlBuilder(0, Config{}).getErrorTestCase(spec)
 
 	c.Assert(want, DeepEquals, got)
 }
+
+func (s *MySuite) TestAttachmentPathKeepsScreenshotNames(c *C) {
+	c.Assert(AttachmentPath("failure.png"), Equals, "attachments/failure.png")
+}
+
+func (s *MySuite) TestAttachmentPathDistinguishesDirectories(c *C) {
+	first := AttachmentPath("/tmp/a/failure.png")
+	second := AttachmentPath("/tmp/b/failure.png")
+
+	c.Assert(first, Not(Equals), second)
+	c.Assert(strings.HasSuffix(first, "-failure.png"), Equals, true)
+	c.Assert(AttachmentPath("/tmp/a/failure.png"), Equals, first)
+}
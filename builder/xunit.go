@@ -0,0 +1,314 @@
+/*----------------------------------------------------------------
+ *  Copyright (c) ThoughtWorks, Inc.
+ *  Licensed under the Apache License, Version 2.0
+ *  See LICENSE in the project root for license information.
+ *----------------------------------------------------------------*/
+
+package builder
+
+import (
+	"encoding/xml"
+	"strings"
+	"time"
+
+	"github.com/getgauge/xml-report/model"
+)
+
+// XUnitFormat is the name of the xUnit.net v2 XML format.
+const XUnitFormat = "xunit"
+
+const (
+	xunitTestFramework = "Gauge"
+	xunitRunDateFormat = "2006-01-02"
+	xunitRunTimeFormat = "15:04:05"
+	xunitCategoryTrait = "Category"
+)
+
+// Values of the xUnit test result attribute.
+const (
+	xunitPass = "Pass"
+	xunitFail = "Fail"
+	xunitSkip = "Skip"
+)
+
+// Values of the xUnit error type and failure exception-type attributes.
+const (
+	xunitFatal                 = "fatal"
+	xunitAssemblyCleanup       = "assembly-cleanup"
+	xunitTestCollectionCleanup = "test-collection-cleanup"
+	xunitAssertionFailure      = "Failure"
+	xunitErrorFailure          = "Error"
+)
+
+// XUnitAssemblies is the root element of an xUnit.net v2 result file. It
+// holds a single assembly for the project.
+type XUnitAssemblies struct {
+	XMLName    xml.Name        `xml:"assemblies"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Assemblies []XUnitAssembly `xml:"assembly"`
+}
+
+// XUnitAssembly is the result of the Gauge project, with a collection per
+// spec.
+type XUnitAssembly struct {
+	Name          string            `xml:"name,attr"`
+	TestFramework string            `xml:"test-framework,attr"`
+	Environment   string            `xml:"environment,attr"`
+	RunDate       string            `xml:"run-date,attr"`
+	RunTime       string            `xml:"run-time,attr"`
+	Time          string            `xml:"time,attr"`
+	Total         int               `xml:"total,attr"`
+	Passed        int               `xml:"passed,attr"`
+	Failed        int               `xml:"failed,attr"`
+	Skipped       int               `xml:"skipped,attr"`
+	ErrorCount    int               `xml:"errors,attr"`
+	Errors        []XUnitError      `xml:"errors>error"`
+	Collections   []XUnitCollection `xml:"collection"`
+}
+
+// XUnitError is an error outside any test, e.g. a failed hook or a spec
+// which failed to parse.
+type XUnitError struct {
+	Type    string       `xml:"type,attr"`
+	Name    string       `xml:"name,attr,omitempty"`
+	Failure XUnitFailure `xml:"failure"`
+}
+
+// XUnitCollection holds the tests of a spec.
+type XUnitCollection struct {
+	Name    string      `xml:"name,attr"`
+	Time    string      `xml:"time,attr"`
+	Total   int         `xml:"total,attr"`
+	Passed  int         `xml:"passed,attr"`
+	Failed  int         `xml:"failed,attr"`
+	Skipped int         `xml:"skipped,attr"`
+	Tests   []XUnitTest `xml:"test"`
+}
+
+// XUnitTest is the result of a scenario, or of one data table row of a table
+// driven scenario.
+type XUnitTest struct {
+	Name       string        `xml:"name,attr"`
+	Type       string        `xml:"type,attr"`
+	Method     string        `xml:"method,attr"`
+	Time       string        `xml:"time,attr"`
+	Result     string        `xml:"result,attr"`
+	SourceFile string        `xml:"source-file,attr,omitempty"`
+	SourceLine int64         `xml:"source-line,attr,omitempty"`
+	Traits     []XUnitTrait  `xml:"traits>trait,omitempty"`
+	Output     *CDataText    `xml:"output,omitempty"`
+	Reason     *CDataText    `xml:"reason,omitempty"`
+	Failure    *XUnitFailure `xml:"failure,omitempty"`
+}
+
+// XUnitTrait is a name/value pair, e.g. a tag reported as a Category.
+type XUnitTrait struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// XUnitFailure holds the message and stack trace of a failure.
+type XUnitFailure struct {
+	ExceptionType string     `xml:"exception-type,attr"`
+	Message       CDataText  `xml:"message"`
+	StackTrace    *CDataText `xml:"stack-trace,omitempty"`
+}
+
+// XUnitBuilder writes the xUnit.net v2 XML report of a suite. Specs are
+// reported as collections of tests, and tags as Category traits.
+type XUnitBuilder struct {
+	config      Config
+	assemblies  XUnitAssemblies
+	attachments []Attachment
+}
+
+func NewXUnitBuilder(config Config) *XUnitBuilder {
+	return &XUnitBuilder{config: config}
+}
+
+// Name returns XUnitFormat.
+func (x *XUnitBuilder) Name() string {
+	return XUnitFormat
+}
+
+// FileExtension returns ".xunit.xml", so that the report does not replace
+// the JUnit result.xml.
+func (x *XUnitBuilder) FileExtension() string {
+	return ".xunit.xml"
+}
+
+// Attachments returns the screenshot files referenced by the last generated report.
+func (x *XUnitBuilder) Attachments() []Attachment {
+	return x.attachments
+}
+
+// Encode returns the xUnit.net v2 XML report of the suite.
+func (x *XUnitBuilder) Encode(report *model.Report) ([]byte, error) {
+	x.attachments = nil
+	specs := report.Specs
+	start, duration := report.StartTime, report.Duration
+	if x.config.Deterministic {
+		specs = sortSpecs(specs)
+		start, duration = time.Unix(0, 0), 0
+	}
+	start = start.UTC()
+	assembly := XUnitAssembly{
+		Name:          sanitizeText(report.ProjectName),
+		TestFramework: xunitTestFramework,
+		Environment:   sanitizeText(report.Environment),
+		RunDate:       start.Format(xunitRunDateFormat),
+		RunTime:       start.Format(xunitRunTimeFormat),
+		Time:          formatTime(duration),
+	}
+	if report.BeforeHook != nil {
+		assembly.Errors = append(assembly.Errors, x.getHookError(xunitFatal, "", report.BeforeHook))
+	}
+	for _, spec := range specs {
+		x.getSpecContent(&assembly, spec)
+	}
+	if report.AfterHook != nil {
+		assembly.Errors = append(assembly.Errors, x.getHookError(xunitAssemblyCleanup, "", report.AfterHook))
+	}
+	assembly.ErrorCount = len(assembly.Errors)
+	x.assemblies = XUnitAssemblies{Timestamp: formatTimestamp(start), Assemblies: []XUnitAssembly{assembly}}
+	truncateAll(x.getTruncatableFields(), x.config.MaxFieldSize)
+	return marshalWithin(x.assemblies, x.config.MaxFileSize, x.getTruncatableFields)
+}
+
+// getSpecContent adds the collection of the spec. Parse errors, validation
+// errors outside any scenario and spec hook failures are reported as errors
+// of the assembly, named after the collection.
+func (x *XUnitBuilder) getSpecContent(assembly *XUnitAssembly, spec *model.Spec) {
+	classname := sanitizeText(getSpecClassname(spec, x.config.ClassnameStrategy))
+	collection := XUnitCollection{Name: classname, Time: x.getTime(spec.Duration)}
+	if spec.HasParseErrors() {
+		assembly.Errors = append(assembly.Errors, XUnitError{Type: xunitFatal, Name: classname, Failure: newXUnitFailure(xunitErrorFailure, joinErrors(spec.Errors), "")})
+		assembly.Collections = append(assembly.Collections, collection)
+		return
+	}
+	if len(spec.ValidationErrors) > 0 {
+		assembly.Errors = append(assembly.Errors, XUnitError{Type: xunitFatal, Name: classname, Failure: newXUnitFailure(xunitErrorFailure, joinErrors(spec.ValidationErrors), "")})
+	}
+	for _, hook := range spec.BeforeHooks {
+		assembly.Errors = append(assembly.Errors, x.getHookError(xunitFatal, classname, hook))
+	}
+	for _, scenario := range spec.Scenarios {
+		test := x.getTest(spec, classname, scenario)
+		collection.Tests = append(collection.Tests, test)
+		collection.Total++
+		switch test.Result {
+		case xunitPass:
+			collection.Passed++
+		case xunitFail:
+			collection.Failed++
+		case xunitSkip:
+			collection.Skipped++
+		}
+	}
+	for _, hook := range spec.AfterHooks {
+		assembly.Errors = append(assembly.Errors, x.getHookError(xunitTestCollectionCleanup, classname, hook))
+	}
+	assembly.Total += collection.Total
+	assembly.Passed += collection.Passed
+	assembly.Failed += collection.Failed
+	assembly.Skipped += collection.Skipped
+	assembly.Collections = append(assembly.Collections, collection)
+}
+
+// getTest returns the test of the scenario.
+func (x *XUnitBuilder) getTest(spec *model.Spec, classname string, scenario *model.Scenario) XUnitTest {
+	test := XUnitTest{
+		Name:       sanitizeText(scenario.Name),
+		Type:       classname,
+		Method:     sanitizeText(scenario.Heading),
+		Time:       x.getTime(scenario.Duration),
+		Result:     xunitPass,
+		SourceFile: sanitizeText(spec.File),
+		SourceLine: scenario.Line,
+	}
+	for _, tag := range uniqueTags(append(append([]string{}, spec.Tags...), scenario.Tags...)) {
+		test.Traits = append(test.Traits, XUnitTrait{Name: xunitCategoryTrait, Value: sanitizeText(tag)})
+	}
+	if scenario.Status == model.Failed {
+		test.Result = xunitFail
+		exceptionType := xunitAssertionFailure
+		if scenario.Errored() {
+			exceptionType = xunitErrorFailure
+		}
+		message, stackTrace := scenario.Failures.Summary()
+		failure := newXUnitFailure(exceptionType, message, stackTrace)
+		test.Failure = &failure
+	} else if len(scenario.Errors) > 0 {
+		test.Result = xunitFail
+		failure := newXUnitFailure(xunitErrorFailure, joinErrors(scenario.Errors), "")
+		test.Failure = &failure
+	} else if scenario.Status == model.Skipped {
+		test.Result = xunitSkip
+		test.Reason = &CDataText{Text: sanitizeText(strings.Join(scenario.SkipReasons, "\n"))}
+	}
+	var lines []string
+	for _, o := range scenario.Output {
+		if o.Screenshot != "" {
+			lines = append(lines, getAttachmentLines(&x.attachments, o.Screenshot)...)
+		} else {
+			lines = append(lines, o.Message)
+		}
+	}
+	test.Output = newCDataText(strings.Join(lines, "\n"))
+	return test
+}
+
+// getHookError reports a failed hook as an error of the given type. For data
+// driven specs the name carries the table row the hook failed for. An error
+// has no output to reference a screenshot from, so hook screenshots are not
+// copied.
+func (x *XUnitBuilder) getHookError(errorType, collection string, hook *model.Hook) XUnitError {
+	name := hook.Name
+	if collection != "" {
+		name = collection + " " + name
+	}
+	return XUnitError{Type: errorType, Name: sanitizeText(name), Failure: newXUnitFailure(xunitErrorFailure, hook.Failure.Message, hook.Failure.StackTrace)}
+}
+
+// getTime returns the time attribute, which is fixed in deterministic mode.
+func (x *XUnitBuilder) getTime(d time.Duration) string {
+	if x.config.Deterministic {
+		return deterministicTime
+	}
+	return formatTime(d)
+}
+
+// getTruncatableFields returns the fields of the report which may be truncated.
+func (x *XUnitBuilder) getTruncatableFields() []truncatableField {
+	var fields []truncatableField
+	addText := func(text *CDataText, keepTail bool) {
+		if text != nil {
+			fields = append(fields, truncatableField{value: &text.Text, keepTail: keepTail})
+		}
+	}
+	addFailure := func(failure *XUnitFailure) {
+		addText(&failure.Message, false)
+		addText(failure.StackTrace, true)
+	}
+	for i := range x.assemblies.Assemblies {
+		assembly := &x.assemblies.Assemblies[i]
+		for j := range assembly.Errors {
+			addFailure(&assembly.Errors[j].Failure)
+		}
+		for j := range assembly.Collections {
+			tests := assembly.Collections[j].Tests
+			for k := range tests {
+				addText(tests[k].Output, false)
+				addText(tests[k].Reason, false)
+				if tests[k].Failure != nil {
+					addFailure(tests[k].Failure)
+				}
+			}
+		}
+	}
+	return fields
+}
+
+func newXUnitFailure(exceptionType, message, stackTrace string) XUnitFailure {
+	return XUnitFailure{ExceptionType: exceptionType, Message: CDataText{Text: sanitizeText(message)}, StackTrace: newCDataText(stackTrace)}
+}
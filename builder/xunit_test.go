@@ -0,0 +1,220 @@
+//go:build linux || darwin
+// +build linux darwin
+
+/*----------------------------------------------------------------
+ *  Copyright (c) ThoughtWorks, Inc.
+ *  Licensed under the Apache License, Version 2.0
+ *  See LICENSE in the project root for license information.
+ *----------------------------------------------------------------*/
+
+package builder
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/getgauge/xml-report/model"
+	"github.com/lestrrat-go/libxml2"
+	"github.com/lestrrat-go/libxml2/xsd"
+	. "gopkg.in/check.v1"
+)
+
+// xunitSchema is transcribed from the documented xUnit.net v2 format, see
+// _testdata/xunit.xsd, not an official schema, so reports are also checked
+// for the attributes the documentation requires, see xunitRequiredAttributes.
+var xunitSchema *xsd.Schema
+
+// xunitRequiredAttributes are the attributes https://xunit.net/docs/format-xml-v2
+// requires of each element.
+var xunitRequiredAttributes = map[string][]string{
+	"assembly":   {"name", "test-framework", "environment", "run-date", "run-time", "time", "total", "passed", "failed", "skipped", "errors"},
+	"collection": {"name", "time", "total", "passed", "failed", "skipped"},
+	"test":       {"name", "type", "method", "time", "result"},
+	"trait":      {"name", "value"},
+	"error":      {"type"},
+}
+
+func init() {
+	schema, err := os.ReadFile(filepath.Join("_testdata", "xunit.xsd"))
+	if err != nil {
+		panic(err)
+	}
+	xunitSchema, err = xsd.Parse(schema)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func encodeXUnit(c *C, builder *XUnitBuilder, report *model.Report) XUnitAssembly {
+	bytes, err := builder.Encode(report)
+	c.Assert(err, Equals, nil)
+	assertXUnitValidation(bytes, c)
+
+	var assemblies XUnitAssemblies
+	c.Assert(xml.Unmarshal(bytes, &assemblies), Equals, nil)
+	c.Assert(len(assemblies.Assemblies), Equals, 1)
+	return assemblies.Assemblies[0]
+}
+
+func (s *MySuite) TestNewEncoderForXUnit(c *C) {
+	encoder, err := NewEncoder(XUnitFormat, Config{})
+
+	c.Assert(err, Equals, nil)
+	c.Assert(encoder.Name(), Equals, "xunit")
+	c.Assert(encoder.FileExtension(), Equals, ".xunit.xml")
+}
+
+func (s *MySuite) TestXUnitReportsScenarioResults(c *C) {
+	assembly := encodeXUnit(c, NewXUnitBuilder(Config{}), newCheckoutReport())
+
+	c.Assert(assembly.Name, Equals, "shop")
+	c.Assert(assembly.TestFramework, Equals, "Gauge")
+	c.Assert(assembly.Environment, Equals, "ci")
+	c.Assert(assembly.RunDate, Equals, "2021-03-04")
+	c.Assert(assembly.RunTime, Equals, "05:06:07")
+	c.Assert(assembly.Time, Equals, "3.000")
+	c.Assert([]int{assembly.Total, assembly.Passed, assembly.Failed, assembly.Skipped, assembly.ErrorCount}, DeepEquals, []int{4, 1, 2, 1, 0})
+	collection := assembly.Collections[0]
+	c.Assert(collection.Name, Equals, "Checkout")
+	c.Assert(collection.Time, Equals, "2.000")
+	c.Assert([]int{collection.Total, collection.Passed, collection.Failed, collection.Skipped}, DeepEquals, []int{4, 1, 2, 1})
+
+	passed, failed, skipped, errored := collection.Tests[0], collection.Tests[1], collection.Tests[2], collection.Tests[3]
+	c.Assert(passed.Result, Equals, "Pass")
+	c.Assert(passed.Type, Equals, "Checkout")
+	c.Assert(passed.Method, Equals, "Pay")
+	c.Assert(passed.Time, Equals, "1.500")
+	c.Assert(passed.SourceFile, Equals, "specs/checkout.spec")
+	c.Assert(passed.SourceLine, Equals, int64(4))
+	c.Assert(passed.Traits, DeepEquals, []XUnitTrait{{Name: "Category", Value: "checkout"}, {Name: "Category", Value: "smoke"}})
+	c.Assert(passed.Failure, IsNil)
+	c.Assert(failed.Result, Equals, "Fail")
+	c.Assert(failed.Failure.ExceptionType, Equals, "Failure")
+	c.Assert(failed.Failure.Message.Text, Equals, "boom")
+	c.Assert(failed.Failure.StackTrace.Text, Equals, "trace")
+	c.Assert(skipped.Result, Equals, "Skip")
+	c.Assert(skipped.Reason.Text, Equals, "not ready")
+	c.Assert(errored.Failure.ExceptionType, Equals, "Error")
+	c.Assert(errored.Failure.StackTrace, IsNil)
+}
+
+func (s *MySuite) TestXUnitReportsHookFailuresAndParseErrorsAsErrors(c *C) {
+	report := &model.Report{
+		BeforeHook: &model.Hook{Name: "BeforeSuite", Failure: model.Failure{Message: "Pre Hook Failure: 'db down'", StackTrace: "suite trace"}, Screenshot: "suite.png", TableRow: -1},
+		AfterHook:  &model.Hook{Name: "AfterSuite", Failure: model.Failure{Message: "Post Hook Failure: 'close'"}, TableRow: -1},
+		Specs: []*model.Spec{
+			{Name: "Broken", Errors: []model.Error{{Type: model.ParseError, File: "specs/broken.spec", Line: 3, Message: "missing heading"}}},
+			{
+				Name:       "Payment",
+				AfterHooks: []*model.Hook{{Name: "AfterSpec | SpecRow: 2", Failure: model.Failure{Message: "after"}, TableRow: 1}},
+				Scenarios:  []*model.Scenario{{Name: "Pay", Heading: "Pay", Status: model.Passed}},
+			},
+		},
+	}
+	builder := NewXUnitBuilder(Config{})
+
+	assembly := encodeXUnit(c, builder, report)
+
+	c.Assert(assembly.ErrorCount, Equals, 4)
+	suiteSetup, parseError, specCleanup, suiteCleanup := assembly.Errors[0], assembly.Errors[1], assembly.Errors[2], assembly.Errors[3]
+	c.Assert(suiteSetup.Type, Equals, "fatal")
+	c.Assert(suiteSetup.Name, Equals, "BeforeSuite")
+	c.Assert(suiteSetup.Failure.Message.Text, Equals, "Pre Hook Failure: 'db down'")
+	c.Assert(suiteSetup.Failure.StackTrace.Text, Equals, "suite trace")
+	c.Assert(parseError.Type, Equals, "fatal")
+	c.Assert(parseError.Name, Equals, "Broken")
+	c.Assert(parseError.Failure.Message.Text, Equals, "[Parse Error] specs/broken.spec:3: missing heading")
+	c.Assert(specCleanup.Type, Equals, "test-collection-cleanup")
+	c.Assert(specCleanup.Name, Equals, "Payment AfterSpec | SpecRow: 2")
+	c.Assert(suiteCleanup.Type, Equals, "assembly-cleanup")
+	c.Assert(len(assembly.Collections), Equals, 2)
+	c.Assert(assembly.Collections[0].Total, Equals, 0)
+	c.Assert(builder.Attachments(), IsNil)
+}
+
+func (s *MySuite) TestXUnitReportsOutputAndScreenshots(c *C) {
+	builder := NewXUnitBuilder(Config{})
+
+	assembly := encodeXUnit(c, builder, newOutputReport())
+
+	c.Assert(assembly.Collections[0].Tests[0].Output.Text, Equals, "paid ]]> done\n[[ATTACHMENT|attachments/paid.png]]\nlogged out")
+	c.Assert(builder.Attachments(), DeepEquals, []Attachment{{File: "paid.png", Path: "attachments/paid.png"}})
+}
+
+func (s *MySuite) TestXUnitDeterministicReportsAreIndependentOfExecution(c *C) {
+	bytes := encodeReorderedReports(c, XUnitFormat)
+	assertXUnitValidation(bytes, c)
+
+	var assemblies XUnitAssemblies
+	c.Assert(xml.Unmarshal(bytes, &assemblies), Equals, nil)
+
+	assembly := assemblies.Assemblies[0]
+	c.Assert(assembly.RunDate, Equals, "1970-01-01")
+	c.Assert(assembly.Time, Equals, "0.000")
+	c.Assert(assembly.Collections[0].Name, Equals, "specs/a.spec")
+	c.Assert(assembly.Collections[1].Tests[0].Name, Equals, "B1")
+}
+
+func (s *MySuite) TestXUnitTruncatesFields(c *C) {
+	assembly := encodeXUnit(c, NewXUnitBuilder(Config{MaxFieldSize: truncatedFieldSize}), newTruncatedReport())
+
+	test := assembly.Collections[0].Tests[0]
+	c.Assert(test.Method, Equals, "Pay")
+	c.Assert(test.Failure.Message.Text, Equals, truncatedMessage)
+	c.Assert(test.Failure.StackTrace.Text, Equals, truncatedStackTrace)
+}
+
+func (s *MySuite) TestXUnitKeepsCDataWithinMaxFileSize(c *C) {
+	bytes, err := NewXUnitBuilder(Config{MaxFileSize: oversizedFileSize}).Encode(newOversizedReport())
+
+	c.Assert(err, Equals, nil)
+	assertXUnitValidation(bytes, c)
+	assertWithinOversizedFileSize(c, bytes)
+	var assemblies XUnitAssemblies
+	c.Assert(xml.Unmarshal(bytes, &assemblies), Equals, nil)
+	for _, test := range assemblies.Assemblies[0].Collections[0].Tests {
+		c.Assert(test.Failure.Message.Text, Equals, "expected <&>")
+		c.Assert(strings.HasPrefix(test.Failure.StackTrace.Text, "at <step> & ]]>\n"), Equals, true)
+		c.Assert(strings.Contains(test.Output.Text, "... [truncated "), Equals, true)
+	}
+}
+
+func assertXUnitValidation(xml []byte, c *C) {
+	assertXUnitRequiredAttributes(xml, c)
+	doc, err := libxml2.Parse(xml)
+	c.Assert(err, Equals, nil)
+	err = xunitSchema.Validate(doc)
+	if err != nil {
+		var errors []string
+		for _, e := range err.(xsd.SchemaValidationError).Errors() {
+			errors = append(errors, e.Error())
+		}
+		c.Assert(err, Equals, nil, Commentf(strings.Join(errors, "\n")))
+	}
+}
+
+func assertXUnitRequiredAttributes(report []byte, c *C) {
+	decoder := xml.NewDecoder(bytes.NewReader(report))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return
+		}
+		c.Assert(err, Equals, nil)
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		attributes := map[string]bool{}
+		for _, attr := range element.Attr {
+			attributes[attr.Name.Local] = true
+		}
+		for _, name := range xunitRequiredAttributes[element.Name.Local] {
+			c.Assert(attributes[name], Equals, true, Commentf("%s has no %s attribute", element.Name.Local, name))
+		}
+	}
+}
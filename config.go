@@ -0,0 +1,170 @@
+/*----------------------------------------------------------------
+ *  Copyright (c) ThoughtWorks, Inc.
+ *  Licensed under the Apache License, Version 2.0
+ *  See LICENSE in the project root for license information.
+ *----------------------------------------------------------------*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getgauge/common"
+	"github.com/getgauge/xml-report/builder"
+	"github.com/getgauge/xml-report/logger"
+)
+
+const (
+	envPropertiesEnvProperty     = "xml_report_env_properties"      // comma separated env vars reported as testsuite properties
+	retryFormatEnvProperty       = "xml_report_retry_format"        // how retried scenarios are reported: surefire or none
+	scenarioNameEnvProperty      = "xml_report_scenario_name"       // testcase name template for scenarios
+	tableScenarioNameEnvProperty = "xml_report_table_scenario_name" // testcase name template for table driven scenarios
+	stableNamesEnvProperty       = "xml_report_stable_names"        // leave table values out of table driven scenario names
+	classnameEnvProperty         = "xml_report_classname"           // testcase classname strategy: heading or directory
+	cdataThresholdEnvProperty    = "xml_report_cdata_threshold"     // size in bytes above which contents are written as CDATA
+	maxFieldSizeEnvProperty      = "xml_report_max_field_size"      // bytes kept of each message, stack trace and output
+	maxFileSizeEnvProperty       = "xml_report_max_file_size"       // size in bytes the report is truncated to fit
+	deterministicEnvProperty     = "xml_report_deterministic"       // sort the report and leave out volatile attributes
+	formatsEnvProperty           = "xml_report_formats"             // comma separated report formats, e.g. junit,nunit,trx
+	pluginJSONFile               = "plugin.json"
+	gaugeVersionTimeout          = 5 * time.Second
+)
+
+// getBuilderConfig reads the report settings from the project's env properties.
+func getBuilderConfig() builder.Config {
+	return builder.Config{
+		Properties:                getReportProperties(),
+		ProjectRoot:               projectRoot,
+		RetryFormat:               getRetryFormat(),
+		ScenarioNameTemplate:      os.Getenv(scenarioNameEnvProperty),
+		TableScenarioNameTemplate: os.Getenv(tableScenarioNameEnvProperty),
+		StableNames:               getBoolEnv(stableNamesEnvProperty),
+		ClassnameStrategy:         strings.ToLower(strings.TrimSpace(os.Getenv(classnameEnvProperty))),
+		CDataThreshold:            getIntEnv(cdataThresholdEnvProperty),
+		MaxFieldSize:              getIntEnv(maxFieldSizeEnvProperty),
+		MaxFileSize:               getIntEnv(maxFileSizeEnvProperty),
+		Deterministic:             getBoolEnv(deterministicEnvProperty),
+	}
+}
+
+// getRetryFormat returns the retry format, defaulting to Surefire when it is
+// not set or unknown.
+func getRetryFormat() string {
+	format := strings.ToLower(strings.TrimSpace(os.Getenv(retryFormatEnvProperty)))
+	switch format {
+	case "":
+		return builder.RetryFormatSurefire
+	case builder.RetryFormatSurefire, builder.RetryFormatNone:
+		return format
+	}
+	logger.Error("Unknown %s '%s', using %s\n", retryFormatEnvProperty, format, builder.RetryFormatSurefire)
+	return builder.RetryFormatSurefire
+}
+
+// getReportFormats returns the formats to write, defaulting to JUnit.
+func getReportFormats() []string {
+	formats := getListEnv(formatsEnvProperty)
+	if len(formats) == 0 {
+		return []string{builder.JUnitFormat}
+	}
+	var unique []string
+	seen := map[string]bool{}
+	for _, format := range formats {
+		format = strings.ToLower(format)
+		if !seen[format] {
+			seen[format] = true
+			unique = append(unique, format)
+		}
+	}
+	return unique
+}
+
+func getReportProperties() []builder.JUnitProperty {
+	var properties []builder.JUnitProperty
+	if version := getGaugeVersion(); version != "" {
+		properties = append(properties, builder.JUnitProperty{Name: "gauge.version", Value: version})
+	}
+	if version := getPluginVersion(); version != "" {
+		properties = append(properties, builder.JUnitProperty{Name: "xml-report.version", Value: version})
+	}
+	for _, name := range getListEnv(envPropertiesEnvProperty) {
+		if value, ok := os.LookupEnv(name); ok {
+			properties = append(properties, builder.JUnitProperty{Name: "env." + name, Value: value})
+		}
+	}
+	return properties
+}
+
+var (
+	gaugeVersion     string
+	gaugeVersionOnce sync.Once
+)
+
+// getGaugeVersion returns the version of the gauge executable, which is asked
+// once per plugin process.
+func getGaugeVersion() string {
+	gaugeVersionOnce.Do(func() { gaugeVersion = readGaugeVersion() })
+	return gaugeVersion
+}
+
+// readGaugeVersion asks the gauge executable for its version. The command is
+// given gaugeVersionTimeout to answer, so that a hanging or missing gauge
+// leaves out the version instead of stalling the report.
+func readGaugeVersion() string {
+	ctx, cancel := context.WithTimeout(context.Background(), gaugeVersionTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "gauge", "-v", "-m").Output()
+	if err != nil {
+		logger.Debug("Failed to get the gauge version: %s\n", err)
+		return ""
+	}
+	var version struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(out, &version); err != nil {
+		logger.Debug("Failed to parse the gauge version: %s\n", err)
+		return ""
+	}
+	return version.Version
+}
+
+func getPluginVersion() string {
+	properties, err := common.GetPluginProperties(filepath.Join(pluginDir, pluginJSONFile))
+	if err != nil {
+		return ""
+	}
+	version, _ := properties["version"].(string)
+	return version
+}
+
+func getBoolEnv(name string) bool {
+	return strings.ToLower(strings.TrimSpace(os.Getenv(name))) == "true"
+}
+
+// getIntEnv returns the value of an env property, or 0 if it is not a non-negative integer.
+func getIntEnv(name string) int {
+	value, err := strconv.Atoi(strings.TrimSpace(os.Getenv(name)))
+	if err != nil || value < 0 {
+		return 0
+	}
+	return value
+}
+
+// getListEnv returns the trimmed, non-empty entries of a comma separated env property.
+func getListEnv(name string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(name), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
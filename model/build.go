@@ -0,0 +1,506 @@
+/*----------------------------------------------------------------
+ *  Copyright (c) ThoughtWorks, Inc.
+ *  Licensed under the Apache License, Version 2.0
+ *  See LICENSE in the project root for license information.
+ *----------------------------------------------------------------*/
+
+package model
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/getgauge/gauge-proto/go/gauge_messages"
+)
+
+const (
+	preHookFailureMsg   = "Pre Hook Failure"
+	postHookFailureMsg  = "Post Hook Failure"
+	executionFailureMsg = "Execution Failure"
+	teardownPrefix      = "Teardown "
+	validationSkipMsg   = "Validation Skip"
+	runtimeSkipMsg      = "Runtime Skip"
+	stepSkipMsg         = "Step Skipped"
+	notExecutedMsg      = "Scenario was not executed"
+)
+
+// Options are the settings the model is built with.
+type Options struct {
+	// ProjectRoot is used to report files relative to the project.
+	ProjectRoot string
+	// Clock is used when the execution result carries no timestamps.
+	// Defaults to time.Now.
+	Clock func() time.Time
+	// ScenarioNameTemplate and TableScenarioNameTemplate name scenarios and
+	// table driven scenarios, see naming.go for placeholders.
+	ScenarioNameTemplate      string
+	TableScenarioNameTemplate string
+	// StableNames leaves table values out of the default table driven names.
+	StableNames bool
+}
+
+type builder struct {
+	options   Options
+	startTime time.Time
+	elapsed   time.Duration
+}
+
+// NewReport builds the model of a suite execution result.
+func NewReport(executionSuiteResult *gauge_messages.SuiteExecutionResult, options Options) *Report {
+	b := &builder{options: options}
+	suiteResult := executionSuiteResult.GetSuiteResult()
+	b.startTime = b.getStartTime(suiteResult.GetTimestampISO(), suiteResult.GetTimestamp())
+	report := &Report{
+		ProjectName: suiteResult.GetProjectName(),
+		Environment: suiteResult.GetEnvironment(),
+		Tags:        suiteResult.GetTags(),
+		StartTime:   b.startTime,
+		Duration:    toDuration(suiteResult.GetExecutionTime()),
+		BeforeHook:  getHook("BeforeSuite", preHookFailureMsg, suiteResult.GetPreHookFailure(), -1),
+		AfterHook:   getHook("AfterSuite", postHookFailureMsg, suiteResult.GetPostHookFailure(), -1),
+		Output:      append(append([]string{}, suiteResult.GetPreHookMessages()...), suiteResult.GetPostHookMessages()...),
+	}
+	for _, result := range suiteResult.GetSpecResults() {
+		report.Specs = append(report.Specs, b.getSpec(result))
+	}
+	return report
+}
+
+func (b *builder) getSpec(result *gauge_messages.ProtoSpecResult) *Spec {
+	protoSpec := result.GetProtoSpec()
+	spec := &Spec{
+		Name:          getSpecName(protoSpec),
+		FileName:      protoSpec.GetFileName(),
+		File:          b.getProjectFile(protoSpec.GetFileName()),
+		Tags:          protoSpec.GetTags(),
+		StartTime:     b.getSpecStartTime(result),
+		Duration:      toDuration(result.GetExecutionTime()),
+		ScenarioCount: int(result.GetScenarioCount()),
+		FailedCount:   int(result.GetScenarioFailedCount()),
+		SkippedCount:  int(result.GetScenarioSkippedCount()),
+		Output:        append(append([]string{}, protoSpec.GetPreHookMessages()...), protoSpec.GetPostHookMessages()...),
+	}
+	for _, e := range result.GetErrors() {
+		spec.Errors = append(spec.Errors, b.getError(e))
+		if e.GetType() == gauge_messages.Error_VALIDATION_ERROR && !isInAnyScenario(e, protoSpec) {
+			spec.ValidationErrors = append(spec.ValidationErrors, b.getError(e))
+		}
+	}
+	spec.BeforeHooks = getSpecHooks(protoSpec, "BeforeSpec", preHookFailureMsg, protoSpec.GetPreHookFailures())
+	spec.AfterHooks = getSpecHooks(protoSpec, "AfterSpec", postHookFailureMsg, protoSpec.GetPostHookFailures())
+	for _, item := range protoSpec.GetItems() {
+		switch item.GetItemType() {
+		case gauge_messages.ProtoItem_Scenario:
+			scenario := item.GetScenario()
+			spec.Scenarios = append(spec.Scenarios, b.getScenario(result, scenario, b.getScenarioName(scenario)))
+		case gauge_messages.ProtoItem_TableDrivenScenario:
+			if scenario := b.getTableDrivenScenario(result, item.GetTableDrivenScenario()); scenario != nil {
+				spec.Scenarios = append(spec.Scenarios, scenario)
+			}
+		}
+	}
+	return spec
+}
+
+func getSpecHooks(spec *gauge_messages.ProtoSpec, name, hookFailureMsg string, failures []*gauge_messages.ProtoHookFailure) []*Hook {
+	var hooks []*Hook
+	for _, failure := range failures {
+		tableRow := -1
+		if spec.GetIsTableDriven() && failure.GetTableRowIndex() >= 0 {
+			tableRow = int(failure.GetTableRowIndex())
+		}
+		hooks = append(hooks, getHook(name, hookFailureMsg, failure, tableRow))
+	}
+	return hooks
+}
+
+// getHook returns the failure of a suite or spec hook, nil if it passed.
+func getHook(name, hookFailureMsg string, failure *gauge_messages.ProtoHookFailure, tableRow int) *Hook {
+	if failure == nil {
+		return nil
+	}
+	if tableRow >= 0 {
+		name = fmt.Sprintf("%s | SpecRow: %d", name, tableRow+1)
+	}
+	return &Hook{
+		Name: name,
+		Failure: Failure{
+			Message:    fmt.Sprintf("%s: '%s'", hookFailureMsg, failure.GetErrorMessage()),
+			StackTrace: failure.GetStackTrace(),
+			Errored:    true,
+		},
+		Screenshot: failure.GetFailureScreenshotFile(),
+		TableRow:   tableRow,
+	}
+}
+
+func (b *builder) getTableDrivenScenario(result *gauge_messages.ProtoSpecResult, tableDriven *gauge_messages.ProtoTableDrivenScenario) *Scenario {
+	protoScenario := tableDriven.GetScenario()
+	if protoScenario == nil {
+		return nil
+	}
+	var specRow, scenarioRow *TableRow
+	if tableDriven.GetIsSpecTableDriven() {
+		specTable := findSpecTable(result) // SpecTable not included in TableDrivenScenario msg; find it in the spec.
+		specRow = newTableRow(specTable, int(tableDriven.GetTableRowIndex()))
+	}
+	if tableDriven.GetIsScenarioTableDriven() {
+		scenarioRow = newTableRow(tableDriven.GetScenarioDataTable(), int(tableDriven.GetScenarioTableRowIndex()))
+	}
+	scenario := b.getScenario(result, protoScenario, b.getTableDrivenScenarioName(protoScenario, specRow, scenarioRow))
+	scenario.SpecRow = specRow
+	scenario.ScenarioRow = scenarioRow
+	return scenario
+}
+
+// Find spec table as the first ProtoTable in the spec items (there is at most one per spec).
+func findSpecTable(result *gauge_messages.ProtoSpecResult) *gauge_messages.ProtoTable {
+	if result == nil || result.GetProtoSpec() == nil {
+		return nil
+	}
+	for _, item := range result.GetProtoSpec().GetItems() {
+		if item.GetItemType() == gauge_messages.ProtoItem_Table {
+			return item.GetTable()
+		}
+	}
+	return nil
+}
+
+func (b *builder) getScenario(result *gauge_messages.ProtoSpecResult, protoScenario *gauge_messages.ProtoScenario, name string) *Scenario {
+	scenario := &Scenario{
+		Name:     name,
+		Heading:  protoScenario.GetScenarioHeading(),
+		ID:       protoScenario.GetID(),
+		Line:     protoScenario.GetSpan().GetStart(),
+		Tags:     protoScenario.GetTags(),
+		Duration: toDuration(protoScenario.GetExecutionTime()),
+		Retries:  int(protoScenario.GetRetriesCount()),
+		Output:   getScenarioOutput(protoScenario),
+	}
+	for _, items := range [][]*gauge_messages.ProtoItem{protoScenario.GetContexts(), protoScenario.GetScenarioItems(), protoScenario.GetTearDownSteps()} {
+		scenario.Steps = append(scenario.Steps, getSteps(items)...)
+	}
+	for _, e := range result.GetErrors() {
+		if e.GetType() == gauge_messages.Error_VALIDATION_ERROR && isInScenario(e, result.GetProtoSpec(), protoScenario) {
+			scenario.Errors = append(scenario.Errors, b.getError(e))
+		}
+	}
+	switch protoScenario.GetExecutionStatus() {
+	case gauge_messages.ExecutionStatus_FAILED:
+		scenario.Status = Failed
+		scenario.Failures = getFailures(protoScenario)
+	case gauge_messages.ExecutionStatus_SKIPPED:
+		scenario.Status = Skipped
+		scenario.SkipReasons = getSkipReasons(protoScenario)
+	}
+	return scenario
+}
+
+// getSteps returns the executed steps and concepts of the given items.
+func getSteps(items []*gauge_messages.ProtoItem) []*Step {
+	var steps []*Step
+	for _, item := range items {
+		switch item.GetItemType() {
+		case gauge_messages.ProtoItem_Step:
+			stepResult := item.GetStep().GetStepExecutionResult()
+			result := stepResult.GetExecutionResult()
+			step := &Step{Text: item.GetStep().GetActualText(), Duration: toDuration(result.GetExecutionTime())}
+			if result.GetFailed() || stepResult.GetPreHookFailure() != nil || stepResult.GetPostHookFailure() != nil {
+				step.Status = Failed
+			} else if stepResult.GetSkipped() || result.GetSkipScenario() {
+				step.Status = Skipped
+			}
+			steps = append(steps, step)
+		case gauge_messages.ProtoItem_Concept:
+			concept := item.GetConcept()
+			result := concept.GetConceptExecutionResult()
+			step := &Step{
+				Text:     concept.GetConceptStep().GetActualText(),
+				Concept:  true,
+				Duration: toDuration(result.GetExecutionResult().GetExecutionTime()),
+				Steps:    getSteps(concept.GetSteps()),
+			}
+			if result.GetExecutionResult().GetFailed() || result.GetPreHookFailure() != nil || result.GetPostHookFailure() != nil {
+				step.Status = Failed
+			} else if result.GetSkipped() {
+				step.Status = Skipped
+			}
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// getSkipReasons tells apart scenarios skipped for validation errors, those
+// skipped at runtime by step code, and those skipped with no reason given.
+// Gauge does not say why the latter were skipped, e.g. after a failed spec
+// hook, so they are reported as not executed.
+func getSkipReasons(scenario *gauge_messages.ProtoScenario) []string {
+	var reasons []string
+	for _, e := range scenario.GetSkipErrors() {
+		reasons = append(reasons, fmt.Sprintf("[%s] %s", validationSkipMsg, e))
+	}
+	for _, items := range [][]*gauge_messages.ProtoItem{scenario.GetContexts(), scenario.GetScenarioItems(), scenario.GetTearDownSteps()} {
+		reasons = append(reasons, getStepSkipReasons(items)...)
+	}
+	if len(reasons) == 0 {
+		reasons = append(reasons, notExecutedMsg)
+	}
+	return reasons
+}
+
+func getStepSkipReasons(items []*gauge_messages.ProtoItem) []string {
+	var reasons []string
+	for _, item := range items {
+		if item.GetItemType() == gauge_messages.ProtoItem_Concept {
+			reasons = append(reasons, getStepSkipReasons(item.GetConcept().GetSteps())...)
+			continue
+		}
+		if item.GetItemType() != gauge_messages.ProtoItem_Step {
+			continue
+		}
+		stepResult := item.GetStep().GetStepExecutionResult()
+		reason := stepResult.GetSkippedReason()
+		if stepResult.GetExecutionResult().GetSkipScenario() {
+			if reason == "" {
+				reason = stepResult.GetExecutionResult().GetErrorMessage()
+			}
+			reasons = append(reasons, fmt.Sprintf("[%s] %s", runtimeSkipMsg, getStepReason(item.GetStep(), reason)))
+		} else if stepResult.GetSkipped() && reason != "" {
+			reasons = append(reasons, fmt.Sprintf("[%s] %s", stepSkipMsg, getStepReason(item.GetStep(), reason)))
+		}
+	}
+	return reasons
+}
+
+func getStepReason(step *gauge_messages.ProtoStep, reason string) string {
+	if step.GetActualText() == "" {
+		return reason
+	}
+	return fmt.Sprintf("%s: %s", step.GetActualText(), reason)
+}
+
+// getScenarioOutput collects the messages written during a scenario run, in
+// execution order: scenario hooks, contexts, scenario steps and teardown steps.
+// Screenshots are listed next to the messages.
+func getScenarioOutput(scenario *gauge_messages.ProtoScenario) []Output {
+	var output []Output
+	output = appendMessages(output, scenario.GetPreHookMessages()...)
+	output = appendScreenshots(output, scenario.GetPreHookScreenshotFiles()...)
+	output = appendScreenshots(output, scenario.GetPreHookFailure().GetFailureScreenshotFile())
+	output = append(output, getStepsOutput(scenario.GetContexts())...)
+	output = append(output, getStepsOutput(scenario.GetScenarioItems())...)
+	output = append(output, getStepsOutput(scenario.GetTearDownSteps())...)
+	output = appendMessages(output, scenario.GetPostHookMessages()...)
+	output = appendScreenshots(output, scenario.GetPostHookScreenshotFiles()...)
+	return appendScreenshots(output, scenario.GetPostHookFailure().GetFailureScreenshotFile())
+}
+
+func getStepsOutput(items []*gauge_messages.ProtoItem) []Output {
+	var output []Output
+	for _, item := range items {
+		if item.GetItemType() == gauge_messages.ProtoItem_Step {
+			step := item.GetStep()
+			stepResult := step.GetStepExecutionResult()
+			result := stepResult.GetExecutionResult()
+			output = appendMessages(output, step.GetPreHookMessages()...)
+			output = appendScreenshots(output, step.GetPreHookScreenshotFiles()...)
+			output = appendScreenshots(output, stepResult.GetPreHookFailure().GetFailureScreenshotFile())
+			output = appendMessages(output, result.GetMessage()...)
+			output = appendScreenshots(output, result.GetScreenshotFiles()...)
+			output = appendScreenshots(output, result.GetFailureScreenshotFile())
+			output = appendMessages(output, step.GetPostHookMessages()...)
+			output = appendScreenshots(output, step.GetPostHookScreenshotFiles()...)
+			output = appendScreenshots(output, stepResult.GetPostHookFailure().GetFailureScreenshotFile())
+		} else if item.GetItemType() == gauge_messages.ProtoItem_Concept {
+			output = append(output, getStepsOutput(item.GetConcept().GetSteps())...)
+		}
+	}
+	return output
+}
+
+func appendMessages(output []Output, messages ...string) []Output {
+	for _, message := range messages {
+		output = append(output, Output{Message: message})
+	}
+	return output
+}
+
+func appendScreenshots(output []Output, files ...string) []Output {
+	for _, file := range files {
+		if file != "" {
+			output = append(output, Output{Screenshot: file})
+		}
+	}
+	return output
+}
+
+// getFailures returns the failures of a failed scenario. A scenario hook
+// failure is reported on its own, as the steps did not run.
+func getFailures(scenario *gauge_messages.ProtoScenario) Failures {
+	failures := Failures{}
+	hookFailure := getFailureFromExecutionResult(scenario.GetScenarioHeading(), scenario.GetPreHookFailure(), scenario.GetPostHookFailure(), nil, "Scenario ")
+	if hookFailure.Message != "" {
+		return append(failures, hookFailure)
+	}
+	failures = append(failures, getFailureFromSteps(scenario.GetContexts(), "")...)
+	failures = append(failures, getFailureFromSteps(scenario.GetScenarioItems(), "")...)
+	return append(failures, getFailureFromSteps(scenario.GetTearDownSteps(), teardownPrefix)...)
+}
+
+// getFailureFromSteps collects the failures of the given steps. section
+// prefixes the failure messages, e.g. to mark teardown steps.
+func getFailureFromSteps(items []*gauge_messages.ProtoItem, section string) []Failure {
+	return getFailureFromItems(items, section, "Step ", nil)
+}
+
+// getFailureFromItems walks steps and nested concepts. conceptPath holds the
+// texts of the enclosing concepts, reported as "Concept A > Concept B > Step".
+func getFailureFromItems(items []*gauge_messages.ProtoItem, section, prefix string, conceptPath []string) []Failure {
+	failures := []Failure{}
+	for _, item := range items {
+		stepFailure := Failure{}
+		if item.GetItemType() == gauge_messages.ProtoItem_Step {
+			preHookFailure := item.GetStep().GetStepExecutionResult().GetPreHookFailure()
+			postHookFailure := item.GetStep().GetStepExecutionResult().GetPostHookFailure()
+			result := item.GetStep().GetStepExecutionResult().GetExecutionResult()
+			name := getBreadcrumb(append(conceptPath, item.GetStep().GetActualText()))
+			stepFailure = getFailureFromExecutionResult(name, preHookFailure, postHookFailure, result, section+prefix)
+		} else if item.GetItemType() == gauge_messages.ProtoItem_Concept {
+			concept := item.GetConcept()
+			path := append(append([]string{}, conceptPath...), concept.GetConceptStep().GetActualText())
+			conceptResult := concept.GetConceptExecutionResult()
+			hookFailure := getFailureFromExecutionResult(getBreadcrumb(path), conceptResult.GetPreHookFailure(), nil, nil, section+"Concept ")
+			if hookFailure.Message != "" {
+				failures = append(failures, hookFailure)
+			}
+			failures = append(failures, getFailureFromItems(concept.GetSteps(), section, "Concept ", path)...)
+			hookFailure = getFailureFromExecutionResult(getBreadcrumb(path), nil, conceptResult.GetPostHookFailure(), nil, section+"Concept ")
+			if hookFailure.Message != "" {
+				failures = append(failures, hookFailure)
+			}
+		}
+		if stepFailure.Message != "" {
+			failures = append(failures, stepFailure)
+		}
+	}
+	return failures
+}
+
+// getBreadcrumb joins the non-empty step texts with " > ".
+func getBreadcrumb(texts []string) string {
+	var parts []string
+	for _, text := range texts {
+		if text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
+
+func getFailureFromExecutionResult(name string, preHookFailure *gauge_messages.ProtoHookFailure,
+	postHookFailure *gauge_messages.ProtoHookFailure, stepExecutionResult *gauge_messages.ProtoExecutionResult, prefix string) Failure {
+	if len(name) > 0 {
+		name = fmt.Sprintf("%s\n", name)
+	}
+	if preHookFailure != nil {
+		return Failure{Message: fmt.Sprintf("%s%s%s: '%s'", name, prefix, preHookFailureMsg, preHookFailure.GetErrorMessage()), StackTrace: preHookFailure.GetStackTrace(), Errored: true}
+	} else if postHookFailure != nil {
+		return Failure{Message: fmt.Sprintf("%s%s%s: '%s'", name, prefix, postHookFailureMsg, postHookFailure.GetErrorMessage()), StackTrace: postHookFailure.GetStackTrace(), Errored: true}
+	} else if stepExecutionResult != nil && stepExecutionResult.GetFailed() {
+		return Failure{
+			Message:    fmt.Sprintf("%s%s%s: '%s'", name, prefix, executionFailureMsg, stepExecutionResult.GetErrorMessage()),
+			StackTrace: stepExecutionResult.GetStackTrace(),
+			Errored:    stepExecutionResult.GetErrorType() != gauge_messages.ProtoExecutionResult_ASSERTION,
+		}
+	}
+	return Failure{}
+}
+
+func (b *builder) getError(e *gauge_messages.Error) Error {
+	t := ParseError
+	if e.GetType() == gauge_messages.Error_VALIDATION_ERROR {
+		t = ValidationError
+	}
+	file := ""
+	if e.GetFilename() != "" {
+		file = b.getProjectFile(e.GetFilename())
+	}
+	return Error{Type: t, File: file, Line: int(e.GetLineNumber()), Message: e.GetMessage()}
+}
+
+func isInAnyScenario(e *gauge_messages.Error, spec *gauge_messages.ProtoSpec) bool {
+	for _, item := range spec.GetItems() {
+		scenario := item.GetScenario()
+		if item.GetItemType() == gauge_messages.ProtoItem_TableDrivenScenario {
+			scenario = item.GetTableDrivenScenario().GetScenario()
+		}
+		if isInScenario(e, spec, scenario) {
+			return true
+		}
+	}
+	return false
+}
+
+// isInScenario reports whether the error is in the spec file within the
+// lines of the scenario. Errors in other files, e.g. concept files, may share
+// the scenario's line numbers and are left to the spec.
+func isInScenario(e *gauge_messages.Error, spec *gauge_messages.ProtoSpec, scenario *gauge_messages.ProtoScenario) bool {
+	span := scenario.GetSpan()
+	if span == nil || filepath.Clean(e.GetFilename()) != filepath.Clean(spec.GetFileName()) {
+		return false
+	}
+	line := int64(e.GetLineNumber())
+	return line >= span.GetStart() && line <= span.GetEnd()
+}
+
+// getProjectFile returns the file relative to the project root when it is
+// within the project, using forward slashes as CI tools expect.
+func (b *builder) getProjectFile(file string) string {
+	if b.options.ProjectRoot != "" {
+		if rel, err := filepath.Rel(b.options.ProjectRoot, file); err == nil && !strings.HasPrefix(rel, "..") {
+			file = rel
+		}
+	}
+	return filepath.ToSlash(file)
+}
+
+// getStartTime parses the first valid RFC 3339 timestamp, falling back to the
+// clock.
+func (b *builder) getStartTime(timestamps ...string) time.Time {
+	for _, timestamp := range timestamps {
+		if t, err := time.Parse(time.RFC3339, timestamp); err == nil {
+			return t
+		}
+	}
+	if b.options.Clock != nil {
+		return b.options.Clock()
+	}
+	return time.Now()
+}
+
+// getSpecStartTime returns when the spec started executing. Without a
+// timestamp of its own, it is derived from the suite start and the execution
+// time of the specs before it.
+func (b *builder) getSpecStartTime(result *gauge_messages.ProtoSpecResult) time.Time {
+	start := b.startTime.Add(b.elapsed)
+	if t, err := time.Parse(time.RFC3339, result.GetTimestampISO()); err == nil {
+		start = t
+	}
+	b.elapsed += toDuration(result.GetExecutionTime())
+	return start
+}
+
+func getSpecName(spec *gauge_messages.ProtoSpec) string {
+	if strings.TrimSpace(spec.GetSpecHeading()) == "" {
+		return filepath.Base(spec.GetFileName())
+	}
+	return spec.GetSpecHeading()
+}
+
+// toDuration converts the milliseconds reported by Gauge.
+func toDuration(milliseconds int64) time.Duration {
+	return time.Duration(milliseconds) * time.Millisecond
+}
@@ -0,0 +1,363 @@
+/*----------------------------------------------------------------
+ *  Copyright (c) ThoughtWorks, Inc.
+ *  Licensed under the Apache License, Version 2.0
+ *  See LICENSE in the project root for license information.
+ *----------------------------------------------------------------*/
+
+package model
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/getgauge/gauge-proto/go/gauge_messages"
+	. "gopkg.in/check.v1"
+)
+
+func newSuiteResult(specResults ...*gauge_messages.ProtoSpecResult) *gauge_messages.SuiteExecutionResult {
+	return &gauge_messages.SuiteExecutionResult{SuiteResult: &gauge_messages.ProtoSuiteResult{SpecResults: specResults}}
+}
+
+func newSpecResult(items ...*gauge_messages.ProtoItem) *gauge_messages.ProtoSpecResult {
+	spec := &gauge_messages.ProtoSpec{SpecHeading: "HEADING", FileName: filepath.Join("project", "specs", "example.spec"), Items: items}
+	return &gauge_messages.ProtoSpecResult{ProtoSpec: spec}
+}
+
+func scenarioItem(scenario *gauge_messages.ProtoScenario) *gauge_messages.ProtoItem {
+	return &gauge_messages.ProtoItem{Scenario: scenario, ItemType: gauge_messages.ProtoItem_Scenario}
+}
+
+func stepItem(text string, stepResult *gauge_messages.ProtoStepExecutionResult) *gauge_messages.ProtoItem {
+	return &gauge_messages.ProtoItem{ItemType: gauge_messages.ProtoItem_Step,
+		Step: &gauge_messages.ProtoStep{ActualText: text, StepExecutionResult: stepResult}}
+}
+
+func conceptItem(text string, steps ...*gauge_messages.ProtoItem) *gauge_messages.ProtoItem {
+	return &gauge_messages.ProtoItem{ItemType: gauge_messages.ProtoItem_Concept, Concept: &gauge_messages.ProtoConcept{
+		ConceptStep: &gauge_messages.ProtoStep{ActualText: text}, Steps: steps}}
+}
+
+func (s *MySuite) TestNewReport(c *C) {
+	message := &gauge_messages.SuiteExecutionResult{SuiteResult: &gauge_messages.ProtoSuiteResult{
+		ProjectName: "project", Environment: "ci", Tags: "smoke", TimestampISO: "2021-03-04T05:06:07Z", ExecutionTime: 1500,
+		PreHookMessages: []string{"before run"}, PostHookMessages: []string{"after run"},
+		PostHookFailure: &gauge_messages.ProtoHookFailure{ErrorMessage: "failed", StackTrace: "trace", FailureScreenshotFile: "run.png"},
+	}}
+
+	report := NewReport(message, Options{})
+
+	c.Assert(report.ProjectName, Equals, "project")
+	c.Assert(report.Environment, Equals, "ci")
+	c.Assert(report.Tags, Equals, "smoke")
+	c.Assert(report.StartTime.Equal(time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)), Equals, true)
+	c.Assert(report.Duration, Equals, 1500*time.Millisecond)
+	c.Assert(report.Output, DeepEquals, []string{"before run", "after run"})
+	c.Assert(report.BeforeHook, IsNil)
+	c.Assert(*report.AfterHook, DeepEquals, Hook{Name: "AfterSuite", Screenshot: "run.png", TableRow: -1,
+		Failure: Failure{Message: "Post Hook Failure: 'failed'", StackTrace: "trace", Errored: true}})
+}
+
+func (s *MySuite) TestNewReportUsesClockWithoutTimestamps(c *C) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	first, second := newSpecResult(), newSpecResult()
+	first.ExecutionTime = 2000
+	second.ExecutionTime = 500
+
+	report := NewReport(newSuiteResult(first, second), Options{Clock: func() time.Time { return now }})
+
+	c.Assert(report.StartTime, Equals, now)
+	c.Assert(report.Specs[0].StartTime, Equals, now)
+	c.Assert(report.Specs[1].StartTime, Equals, now.Add(2*time.Second))
+	c.Assert(report.Specs[1].Duration, Equals, 500*time.Millisecond)
+}
+
+func (s *MySuite) TestNewReportSpec(c *C) {
+	result := newSpecResult()
+	result.ProtoSpec.Tags = []string{"tag1", "tag2"}
+	result.ProtoSpec.PreHookMessages = []string{"before spec"}
+	result.ProtoSpec.PostHookMessages = []string{"after spec"}
+	result.ScenarioCount, result.ScenarioFailedCount, result.ScenarioSkippedCount = 5, 2, 1
+	result.TimestampISO = "2021-03-04T05:06:07+05:30"
+
+	spec := NewReport(newSuiteResult(result), Options{ProjectRoot: "project"}).Specs[0]
+
+	c.Assert(spec.Name, Equals, "HEADING")
+	c.Assert(spec.FileName, Equals, filepath.Join("project", "specs", "example.spec"))
+	c.Assert(spec.File, Equals, "specs/example.spec")
+	c.Assert(spec.Tags, DeepEquals, []string{"tag1", "tag2"})
+	c.Assert(spec.ScenarioCount, Equals, 5)
+	c.Assert(spec.FailedCount, Equals, 2)
+	c.Assert(spec.SkippedCount, Equals, 1)
+	c.Assert(spec.Output, DeepEquals, []string{"before spec", "after spec"})
+	c.Assert(spec.StartTime.Format(time.RFC3339), Equals, "2021-03-04T05:06:07+05:30")
+}
+
+func (s *MySuite) TestGetSpecNameWhenHeadingIsPresent(c *C) {
+	c.Assert(getSpecName(&gauge_messages.ProtoSpec{SpecHeading: "heading"}), Equals, "heading")
+}
+
+func (s *MySuite) TestGetSpecNameWhenHeadingIsNotPresent(c *C) {
+	got := getSpecName(&gauge_messages.ProtoSpec{FileName: filepath.Join("specs", "specs1", "example.spec")})
+
+	c.Assert(got, Equals, "example.spec")
+}
+
+func (s *MySuite) TestNewReportSpecHooks(c *C) {
+	result := newSpecResult()
+	result.ProtoSpec.IsTableDriven = true
+	result.ProtoSpec.PreHookFailures = []*gauge_messages.ProtoHookFailure{{ErrorMessage: "row 2", TableRowIndex: 1}}
+	result.ProtoSpec.PostHookFailures = []*gauge_messages.ProtoHookFailure{{ErrorMessage: "after", TableRowIndex: 0, FailureScreenshotFile: "after.png"}}
+
+	spec := NewReport(newSuiteResult(result), Options{}).Specs[0]
+
+	c.Assert(spec.BeforeHooks, HasLen, 1)
+	c.Assert(spec.BeforeHooks[0].Name, Equals, "BeforeSpec | SpecRow: 2")
+	c.Assert(spec.BeforeHooks[0].TableRow, Equals, 1)
+	c.Assert(spec.BeforeHooks[0].Failure.Message, Equals, "Pre Hook Failure: 'row 2'")
+	c.Assert(spec.AfterHooks[0].Name, Equals, "AfterSpec | SpecRow: 1")
+	c.Assert(spec.AfterHooks[0].TableRow, Equals, 0)
+	c.Assert(spec.AfterHooks[0].Screenshot, Equals, "after.png")
+}
+
+func (s *MySuite) TestNewReportSpecHooksOfSpecWithoutTable(c *C) {
+	result := newSpecResult()
+	result.ProtoSpec.PreHookFailures = []*gauge_messages.ProtoHookFailure{{ErrorMessage: "failed"}}
+
+	spec := NewReport(newSuiteResult(result), Options{}).Specs[0]
+
+	c.Assert(spec.BeforeHooks[0].TableRow, Equals, -1)
+}
+
+func (s *MySuite) TestNewReportErrors(c *C) {
+	scenario := &gauge_messages.ProtoScenario{ScenarioHeading: "Scenario", ExecutionStatus: gauge_messages.ExecutionStatus_SKIPPED,
+		Span: &gauge_messages.Span{Start: 5, End: 8}}
+	result := newSpecResult(scenarioItem(scenario))
+	file := filepath.Join("project", "specs", "example.spec")
+	result.Errors = []*gauge_messages.Error{
+		{Type: gauge_messages.Error_PARSE_ERROR, Message: "parse error"},
+		{Type: gauge_messages.Error_VALIDATION_ERROR, Filename: file, LineNumber: 3, Message: "context"},
+		{Type: gauge_messages.Error_VALIDATION_ERROR, Filename: file, LineNumber: 6, Message: "step"},
+	}
+
+	spec := NewReport(newSuiteResult(result), Options{ProjectRoot: "project"}).Specs[0]
+
+	c.Assert(spec.Errors, DeepEquals, []Error{
+		{Type: ParseError, Message: "parse error"},
+		{Type: ValidationError, File: "specs/example.spec", Line: 3, Message: "context"},
+		{Type: ValidationError, File: "specs/example.spec", Line: 6, Message: "step"},
+	})
+	c.Assert(spec.ValidationErrors, DeepEquals, []Error{{Type: ValidationError, File: "specs/example.spec", Line: 3, Message: "context"}})
+	c.Assert(spec.Scenarios[0].Errors, DeepEquals, []Error{{Type: ValidationError, File: "specs/example.spec", Line: 6, Message: "step"}})
+}
+
+func (s *MySuite) TestNewReportErrorsInConceptFiles(c *C) {
+	scenario := &gauge_messages.ProtoScenario{ScenarioHeading: "Scenario", ExecutionStatus: gauge_messages.ExecutionStatus_SKIPPED,
+		Span: &gauge_messages.Span{Start: 5, End: 8}}
+	result := newSpecResult(scenarioItem(scenario))
+	result.Errors = []*gauge_messages.Error{
+		{Type: gauge_messages.Error_VALIDATION_ERROR, Filename: filepath.Join("project", "concepts", "login.cpt"), LineNumber: 6, Message: "concept step"},
+	}
+
+	spec := NewReport(newSuiteResult(result), Options{ProjectRoot: "project"}).Specs[0]
+
+	c.Assert(spec.ValidationErrors, DeepEquals, []Error{{Type: ValidationError, File: "concepts/login.cpt", Line: 6, Message: "concept step"}})
+	c.Assert(spec.Scenarios[0].Errors, IsNil)
+}
+
+func (s *MySuite) TestNewReportScenario(c *C) {
+	scenario := &gauge_messages.ProtoScenario{ScenarioHeading: "Scenario", ID: "example.spec:4", Tags: []string{"smoke"},
+		Span: &gauge_messages.Span{Start: 4, End: 9}, ExecutionStatus: gauge_messages.ExecutionStatus_PASSED, ExecutionTime: 250, RetriesCount: 2}
+
+	got := NewReport(newSuiteResult(newSpecResult(scenarioItem(scenario))), Options{ScenarioNameTemplate: "{id}: {heading}"}).Specs[0].Scenarios[0]
+
+	c.Assert(got.Name, Equals, "example.spec:4: Scenario")
+	c.Assert(got.Heading, Equals, "Scenario")
+	c.Assert(got.ID, Equals, "example.spec:4")
+	c.Assert(got.Line, Equals, int64(4))
+	c.Assert(got.Tags, DeepEquals, []string{"smoke"})
+	c.Assert(got.Status, Equals, Passed)
+	c.Assert(got.Duration, Equals, 250*time.Millisecond)
+	c.Assert(got.Retries, Equals, 2)
+	c.Assert(got.Failures, IsNil)
+	c.Assert(got.SkipReasons, IsNil)
+	c.Assert(got.SpecRow, IsNil)
+	c.Assert(got.ScenarioRow, IsNil)
+}
+
+func (s *MySuite) TestNewReportTableDrivenScenario(c *C) {
+	specTable := &gauge_messages.ProtoTable{
+		Headers: &gauge_messages.ProtoTableRow{Cells: []string{"name"}},
+		Rows:    []*gauge_messages.ProtoTableRow{{Cells: []string{"john"}}, {Cells: []string{"mike"}}},
+	}
+	scenarioTable := &gauge_messages.ProtoTable{
+		Headers: &gauge_messages.ProtoTableRow{Cells: []string{"city"}},
+		Rows:    []*gauge_messages.ProtoTableRow{{Cells: []string{"London"}}},
+	}
+	tableDriven := &gauge_messages.ProtoTableDrivenScenario{Scenario: &gauge_messages.ProtoScenario{ScenarioHeading: "Scenario"},
+		IsSpecTableDriven: true, TableRowIndex: 1, IsScenarioTableDriven: true, ScenarioDataTable: scenarioTable, ScenarioTableRowIndex: 0}
+	result := newSpecResult(
+		&gauge_messages.ProtoItem{ItemType: gauge_messages.ProtoItem_Table, Table: specTable},
+		&gauge_messages.ProtoItem{ItemType: gauge_messages.ProtoItem_TableDrivenScenario, TableDrivenScenario: tableDriven},
+		&gauge_messages.ProtoItem{ItemType: gauge_messages.ProtoItem_TableDrivenScenario, TableDrivenScenario: &gauge_messages.ProtoTableDrivenScenario{}},
+	)
+
+	scenarios := NewReport(newSuiteResult(result), Options{}).Specs[0].Scenarios
+
+	c.Assert(scenarios, HasLen, 1)
+	c.Assert(scenarios[0].Name, Equals, "Scenario | SpecRow: 2: [name: mike] ScnRow: 1: [city: London]")
+	c.Assert(*scenarios[0].SpecRow, DeepEquals, TableRow{Index: 1, Headers: []string{"name"}, Cells: []string{"mike"}})
+	c.Assert(*scenarios[0].ScenarioRow, DeepEquals, TableRow{Index: 0, Headers: []string{"city"}, Cells: []string{"London"}})
+}
+
+func (s *MySuite) TestNewReportFailures(c *C) {
+	assertion := &gauge_messages.ProtoStepExecutionResult{ExecutionResult: &gauge_messages.ProtoExecutionResult{
+		Failed: true, ErrorMessage: "expected", StackTrace: "assertion trace", ExecutionTime: 10}}
+	exception := &gauge_messages.ProtoStepExecutionResult{ExecutionResult: &gauge_messages.ProtoExecutionResult{
+		Failed: true, ErrorMessage: "exception", StackTrace: "exception trace", ErrorType: gauge_messages.ProtoExecutionResult_VERIFICATION}}
+	scenario := &gauge_messages.ProtoScenario{ScenarioHeading: "Scenario", ExecutionStatus: gauge_messages.ExecutionStatus_FAILED,
+		Contexts:      []*gauge_messages.ProtoItem{stepItem("Context", assertion)},
+		ScenarioItems: []*gauge_messages.ProtoItem{conceptItem("Concept", stepItem("Inner", exception))},
+		TearDownSteps: []*gauge_messages.ProtoItem{stepItem("Cleanup", assertion)},
+	}
+
+	got := NewReport(newSuiteResult(newSpecResult(scenarioItem(scenario))), Options{}).Specs[0].Scenarios[0]
+
+	c.Assert(got.Status, Equals, Failed)
+	c.Assert(got.Failures, DeepEquals, Failures{
+		{Message: "Context\nStep Execution Failure: 'expected'", StackTrace: "assertion trace"},
+		{Message: "Concept > Inner\nConcept Execution Failure: 'exception'", StackTrace: "exception trace", Errored: true},
+		{Message: "Cleanup\nTeardown Step Execution Failure: 'expected'", StackTrace: "assertion trace"},
+	})
+	c.Assert(got.Errored(), Equals, true)
+}
+
+func (s *MySuite) TestNewReportScenarioHookFailure(c *C) {
+	failed := &gauge_messages.ProtoStepExecutionResult{ExecutionResult: &gauge_messages.ProtoExecutionResult{Failed: true, ErrorMessage: "expected"}}
+	scenario := &gauge_messages.ProtoScenario{ScenarioHeading: "Scenario", ExecutionStatus: gauge_messages.ExecutionStatus_FAILED,
+		PreHookFailure: &gauge_messages.ProtoHookFailure{ErrorMessage: "hook failed", StackTrace: "hook trace"},
+		ScenarioItems:  []*gauge_messages.ProtoItem{stepItem("Step", failed)},
+	}
+
+	got := NewReport(newSuiteResult(newSpecResult(scenarioItem(scenario))), Options{}).Specs[0].Scenarios[0]
+
+	c.Assert(got.Failures, DeepEquals, Failures{{Message: "Scenario\nScenario Pre Hook Failure: 'hook failed'", StackTrace: "hook trace", Errored: true}})
+}
+
+func (s *MySuite) TestGetFailureFromExecutionResult(c *C) {
+	info := getFailureFromExecutionResult("", nil, nil, nil, "PREFIX ")
+
+	c.Assert(info.Message, Equals, "")
+	c.Assert(info.StackTrace, Equals, "")
+
+	failure := &gauge_messages.ProtoHookFailure{StackTrace: "StackTrace", ErrorMessage: "ErrorMessage"}
+	hookInfo := getFailureFromExecutionResult("", failure, nil, nil, "PREFIX ")
+
+	c.Assert(hookInfo.Message, Equals, "PREFIX "+preHookFailureMsg+": 'ErrorMessage'")
+	c.Assert(hookInfo.StackTrace, Equals, "StackTrace")
+
+	hookInfo = getFailureFromExecutionResult("", nil, failure, nil, "PREFIX ")
+
+	c.Assert(hookInfo.Message, Equals, "PREFIX "+postHookFailureMsg+": 'ErrorMessage'")
+	c.Assert(hookInfo.StackTrace, Equals, "StackTrace")
+
+	hookInfo = getFailureFromExecutionResult("Foo", nil, failure, nil, "PREFIX ")
+
+	c.Assert(hookInfo.Message, Equals, "Foo\nPREFIX "+postHookFailureMsg+": 'ErrorMessage'")
+	c.Assert(hookInfo.StackTrace, Equals, "StackTrace")
+
+	executionFailure := &gauge_messages.ProtoExecutionResult{StackTrace: "StackTrace", ErrorMessage: "ErrorMessage", Failed: true}
+	execInfo := getFailureFromExecutionResult("Foo", nil, nil, executionFailure, "PREFIX ")
+
+	c.Assert(execInfo.Message, Equals, "Foo\nPREFIX "+executionFailureMsg+": 'ErrorMessage'")
+	c.Assert(execInfo.StackTrace, Equals, "StackTrace")
+}
+
+func (s *MySuite) TestGetFailureFromStepsOfNestedConcepts(c *C) {
+	stepType := gauge_messages.ProtoItem_Step
+	cptType := gauge_messages.ProtoItem_Concept
+	result := &gauge_messages.ProtoExecutionResult{Failed: true, ErrorMessage: "boom", StackTrace: "stacktrace"}
+	step := &gauge_messages.ProtoStep{ActualText: "Pay with card", StepExecutionResult: &gauge_messages.ProtoStepExecutionResult{ExecutionResult: result}}
+	inner := &gauge_messages.ProtoConcept{
+		ConceptStep:            &gauge_messages.ProtoStep{ActualText: "Checkout"},
+		Steps:                  []*gauge_messages.ProtoItem{{Step: step, ItemType: stepType}},
+		ConceptExecutionResult: &gauge_messages.ProtoStepExecutionResult{PostHookFailure: &gauge_messages.ProtoHookFailure{ErrorMessage: "hook", StackTrace: "hook stacktrace"}},
+	}
+	outer := &gauge_messages.ProtoConcept{
+		ConceptStep: &gauge_messages.ProtoStep{ActualText: "Buy a book"},
+		Steps:       []*gauge_messages.ProtoItem{{Concept: inner, ItemType: cptType}},
+	}
+
+	failures := getFailureFromSteps([]*gauge_messages.ProtoItem{{Concept: outer, ItemType: cptType}}, "")
+
+	c.Assert(failures, DeepEquals, []Failure{
+		{Message: "Buy a book > Checkout > Pay with card\nConcept " + executionFailureMsg + ": 'boom'", StackTrace: "stacktrace"},
+		{Message: "Buy a book > Checkout\nConcept " + postHookFailureMsg + ": 'hook'", StackTrace: "hook stacktrace", Errored: true},
+	})
+}
+
+func (s *MySuite) TestNewReportSkipReasons(c *C) {
+	skipped := &gauge_messages.ProtoStepExecutionResult{Skipped: true, SkippedReason: "not run"}
+	runtime := &gauge_messages.ProtoStepExecutionResult{ExecutionResult: &gauge_messages.ProtoExecutionResult{SkipScenario: true, ErrorMessage: "flag is off"}}
+	scenario := &gauge_messages.ProtoScenario{ScenarioHeading: "Scenario", ExecutionStatus: gauge_messages.ExecutionStatus_SKIPPED,
+		SkipErrors:    []string{"Step implementation not found"},
+		ScenarioItems: []*gauge_messages.ProtoItem{conceptItem("Concept", stepItem("Check flag", runtime)), stepItem("Log in", skipped)},
+	}
+	unexplained := &gauge_messages.ProtoScenario{ScenarioHeading: "Unexplained", ExecutionStatus: gauge_messages.ExecutionStatus_SKIPPED}
+
+	scenarios := NewReport(newSuiteResult(newSpecResult(scenarioItem(scenario), scenarioItem(unexplained))), Options{}).Specs[0].Scenarios
+
+	c.Assert(scenarios[0].Status, Equals, Skipped)
+	c.Assert(scenarios[0].SkipReasons, DeepEquals, []string{
+		"[Validation Skip] Step implementation not found",
+		"[Runtime Skip] Check flag: flag is off",
+		"[Step Skipped] Log in: not run",
+	})
+	c.Assert(scenarios[1].SkipReasons, DeepEquals, []string{"Scenario was not executed"})
+}
+
+func (s *MySuite) TestNewReportSteps(c *C) {
+	passed := &gauge_messages.ProtoStepExecutionResult{ExecutionResult: &gauge_messages.ProtoExecutionResult{ExecutionTime: 20}}
+	failed := &gauge_messages.ProtoStepExecutionResult{ExecutionResult: &gauge_messages.ProtoExecutionResult{Failed: true}}
+	skipped := &gauge_messages.ProtoStepExecutionResult{Skipped: true}
+	concept := conceptItem("Concept", stepItem("Inner", failed))
+	concept.Concept.ConceptExecutionResult = &gauge_messages.ProtoStepExecutionResult{ExecutionResult: &gauge_messages.ProtoExecutionResult{Failed: true, ExecutionTime: 30}}
+	scenario := &gauge_messages.ProtoScenario{ScenarioHeading: "Scenario",
+		Contexts:      []*gauge_messages.ProtoItem{stepItem("Context", passed)},
+		ScenarioItems: []*gauge_messages.ProtoItem{concept, {ItemType: gauge_messages.ProtoItem_Comment}, stepItem("Skipped", skipped)},
+		TearDownSteps: []*gauge_messages.ProtoItem{stepItem("Teardown", passed)},
+	}
+
+	steps := NewReport(newSuiteResult(newSpecResult(scenarioItem(scenario))), Options{}).Specs[0].Scenarios[0].Steps
+
+	c.Assert(steps, DeepEquals, []*Step{
+		{Text: "Context", Status: Passed, Duration: 20 * time.Millisecond},
+		{Text: "Concept", Concept: true, Status: Failed, Duration: 30 * time.Millisecond, Steps: []*Step{{Text: "Inner", Status: Failed}}},
+		{Text: "Skipped", Status: Skipped},
+		{Text: "Teardown", Status: Passed, Duration: 20 * time.Millisecond},
+	})
+}
+
+func (s *MySuite) TestNewReportScenarioOutput(c *C) {
+	step := stepItem("Step", &gauge_messages.ProtoStepExecutionResult{
+		ExecutionResult: &gauge_messages.ProtoExecutionResult{Message: []string{"step message"}, ScreenshotFiles: []string{"step.png"}, FailureScreenshotFile: "failure.png"},
+		PreHookFailure:  &gauge_messages.ProtoHookFailure{FailureScreenshotFile: "step-hook.png"},
+	})
+	step.Step.PreHookMessages = []string{"before step"}
+	step.Step.PostHookMessages = []string{"after step"}
+	scenario := &gauge_messages.ProtoScenario{ScenarioHeading: "Scenario",
+		PreHookMessages: []string{"before scenario"}, PreHookScreenshotFiles: []string{"before.png"},
+		ScenarioItems:    []*gauge_messages.ProtoItem{conceptItem("Concept", step)},
+		PostHookMessages: []string{"after scenario"},
+		PostHookFailure:  &gauge_messages.ProtoHookFailure{FailureScreenshotFile: "after.png"},
+	}
+
+	output := NewReport(newSuiteResult(newSpecResult(scenarioItem(scenario))), Options{}).Specs[0].Scenarios[0].Output
+
+	c.Assert(output, DeepEquals, []Output{
+		{Message: "before scenario"}, {Screenshot: "before.png"},
+		{Message: "before step"}, {Screenshot: "step-hook.png"},
+		{Message: "step message"}, {Screenshot: "step.png"}, {Screenshot: "failure.png"},
+		{Message: "after step"},
+		{Message: "after scenario"}, {Screenshot: "after.png"},
+	})
+}
@@ -0,0 +1,242 @@
+/*----------------------------------------------------------------
+ *  Copyright (c) ThoughtWorks, Inc.
+ *  Licensed under the Apache License, Version 2.0
+ *  See LICENSE in the project root for license information.
+ *----------------------------------------------------------------*/
+
+// Package model is a format-neutral view of a Gauge suite execution result.
+// It is built once from the result and written out by each report format,
+// so that failures, skip reasons, data table rows, hooks and output are
+// extracted the same way for every format.
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Status is the outcome of a scenario or step.
+type Status int
+
+const (
+	Passed Status = iota
+	Failed
+	Skipped
+)
+
+func (s Status) String() string {
+	switch s {
+	case Failed:
+		return "failed"
+	case Skipped:
+		return "skipped"
+	}
+	return "passed"
+}
+
+// Report is the result of a Gauge suite execution.
+type Report struct {
+	ProjectName string
+	Environment string
+	// Tags is the tag expression the specs were filtered by.
+	Tags      string
+	StartTime time.Time
+	Duration  time.Duration
+	Specs     []*Spec
+	// BeforeHook and AfterHook are the failures of the BeforeSuite and
+	// AfterSuite hooks, nil if the hooks passed.
+	BeforeHook *Hook
+	AfterHook  *Hook
+	// Output holds the messages written by the suite hooks.
+	Output []string
+}
+
+// Spec is the result of a spec file.
+type Spec struct {
+	Name string
+	// FileName is the spec file as reported by Gauge, File is the same file
+	// relative to the project root, using forward slashes.
+	FileName  string
+	File      string
+	Tags      []string
+	StartTime time.Time
+	Duration  time.Duration
+	// The scenario counts as reported by Gauge.
+	ScenarioCount int
+	FailedCount   int
+	SkippedCount  int
+	// Errors holds all parse and validation errors of the spec.
+	Errors []Error
+	// ValidationErrors holds the validation errors which are not within any
+	// scenario, e.g. in the spec's context steps.
+	ValidationErrors []Error
+	// BeforeHooks and AfterHooks are the failures of the BeforeSpec and
+	// AfterSpec hooks, one per data table row for table driven specs.
+	BeforeHooks []*Hook
+	AfterHooks  []*Hook
+	// Output holds the messages written by the spec hooks.
+	Output    []string
+	Scenarios []*Scenario
+}
+
+// HasParseErrors reports whether the spec failed to parse, in which case its
+// scenarios were not run.
+func (s *Spec) HasParseErrors() bool {
+	for _, e := range s.Errors {
+		if e.Type == ParseError {
+			return true
+		}
+	}
+	return false
+}
+
+// Scenario is the result of a scenario, or of one data table row of a table
+// driven scenario.
+type Scenario struct {
+	// Name is the heading, or the name rendered from the configured template.
+	Name     string
+	Heading  string
+	ID       string
+	Line     int64
+	Tags     []string
+	Status   Status
+	Duration time.Duration
+	// Retries is the number of times the scenario was retried.
+	Retries int
+	// SpecRow and ScenarioRow are the data table rows the scenario was run
+	// for, nil if it is not table driven.
+	SpecRow     *TableRow
+	ScenarioRow *TableRow
+	// Failures of a failed scenario, in execution order.
+	Failures Failures
+	// SkipReasons of a skipped scenario.
+	SkipReasons []string
+	// Errors holds the validation errors within the scenario.
+	Errors []Error
+	// Steps holds the contexts, steps and teardown steps of the scenario.
+	Steps []*Step
+	// Output holds the messages and screenshots of the scenario, its hooks
+	// and its steps, in execution order.
+	Output []Output
+}
+
+// Errored reports whether any failure is an error rather than an assertion
+// failure.
+func (s *Scenario) Errored() bool {
+	for _, f := range s.Failures {
+		if f.Errored {
+			return true
+		}
+	}
+	return false
+}
+
+// Step is an executed step, or a concept and its steps.
+type Step struct {
+	Text     string
+	Concept  bool
+	Status   Status
+	Duration time.Duration
+	// Steps of a concept.
+	Steps []*Step
+}
+
+// Failure describes a single failure of a scenario. Errored is set for hook
+// failures and exceptions other than assertion failures.
+type Failure struct {
+	Message    string
+	StackTrace string
+	Errored    bool
+}
+
+// Failures of a scenario, in execution order.
+type Failures []Failure
+
+// Summary returns the message and stack trace which report all failures: the
+// ones of the only failure, or "Multiple failures" and the text of each.
+func (f Failures) Summary() (message, stackTrace string) {
+	if len(f) == 1 {
+		return f[0].Message, f[0].StackTrace
+	}
+	return "Multiple failures", f.Text()
+}
+
+// Text returns the message and stack trace of each failure, separated by
+// blank lines.
+func (f Failures) Text() string {
+	var text []string
+	for _, failure := range f {
+		text = append(text, strings.TrimSpace(fmt.Sprintf("%s\n%s", failure.Message, failure.StackTrace)))
+	}
+	return strings.Join(text, "\n\n")
+}
+
+// Hook is a failed hook.
+type Hook struct {
+	// Name is one of BeforeSuite, AfterSuite, BeforeSpec or AfterSpec. For
+	// data driven specs it is followed by the table row the hook failed for,
+	// e.g. "AfterSpec | SpecRow: 2".
+	Name    string
+	Failure Failure
+	// Screenshot is the screenshot taken on failure, if any.
+	Screenshot string
+	// TableRow is the data table row a spec hook failed for, -1 if the spec
+	// is not table driven.
+	TableRow int
+}
+
+// Output is either a message or a screenshot file.
+type Output struct {
+	Message    string
+	Screenshot string
+}
+
+// ErrorType tells apart parse and validation errors.
+type ErrorType int
+
+const (
+	ParseError ErrorType = iota
+	ValidationError
+)
+
+// Error is a parse or validation error of a spec.
+type Error struct {
+	Type ErrorType
+	// File is relative to the project root, using forward slashes.
+	File    string
+	Line    int
+	Message string
+}
+
+// String formats the error as "[<Type> Error] <file>:<line>: <message>",
+// leaving out an unknown location.
+func (e Error) String() string {
+	t := "Parse"
+	if e.Type == ValidationError {
+		t = "Validation"
+	}
+	location := ""
+	if e.File != "" {
+		location = fmt.Sprintf("%s:%d: ", e.File, e.Line)
+	}
+	return fmt.Sprintf("[%s Error] %s%s", t, location, e.Message)
+}
+
+// TableRow is the data table row a table driven scenario was run for.
+type TableRow struct {
+	// Index is 0-based.
+	Index   int
+	Headers []string
+	Cells   []string
+}
+
+// Value returns the value of the named column.
+func (r *TableRow) Value(header string) (string, bool) {
+	for i, h := range r.Headers {
+		if h == header && i < len(r.Cells) {
+			return r.Cells[i], true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,82 @@
+/*----------------------------------------------------------------
+ *  Copyright (c) ThoughtWorks, Inc.
+ *  Licensed under the Apache License, Version 2.0
+ *  See LICENSE in the project root for license information.
+ *----------------------------------------------------------------*/
+
+package model
+
+import (
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type MySuite struct{}
+
+var _ = Suite(&MySuite{})
+
+func (s *MySuite) TestStatusString(c *C) {
+	c.Assert(Passed.String(), Equals, "passed")
+	c.Assert(Failed.String(), Equals, "failed")
+	c.Assert(Skipped.String(), Equals, "skipped")
+}
+
+func (s *MySuite) TestErrorString(c *C) {
+	c.Assert(Error{Type: ParseError, Message: "parse error"}.String(), Equals, "[Parse Error] parse error")
+	c.Assert(Error{Type: ValidationError, File: "specs/example.spec", Line: 4, Message: "Step implementation not found"}.String(), Equals,
+		"[Validation Error] specs/example.spec:4: Step implementation not found")
+}
+
+func (s *MySuite) TestHasParseErrors(c *C) {
+	spec := &Spec{Errors: []Error{{Type: ParseError}, {Type: ValidationError}}}
+
+	c.Assert(spec.HasParseErrors(), Equals, true)
+}
+
+func (s *MySuite) TestHasParseErrorsWithNoErrors(c *C) {
+	c.Assert((&Spec{}).HasParseErrors(), Equals, false)
+}
+
+func (s *MySuite) TestHasParseErrorsWithOnlyValidationErrors(c *C) {
+	spec := &Spec{Errors: []Error{{Type: ValidationError}, {Type: ValidationError}}}
+
+	c.Assert(spec.HasParseErrors(), Equals, false)
+}
+
+func (s *MySuite) TestScenarioErrored(c *C) {
+	c.Assert((&Scenario{Failures: []Failure{{Message: "assertion"}}}).Errored(), Equals, false)
+	c.Assert((&Scenario{Failures: []Failure{{Message: "assertion"}, {Message: "exception", Errored: true}}}).Errored(), Equals, true)
+}
+
+func (s *MySuite) TestTableRowValue(c *C) {
+	row := &TableRow{Headers: []string{"name", "age", "city"}, Cells: []string{"john", "20"}}
+
+	name, ok := row.Value("name")
+	c.Assert(name, Equals, "john")
+	c.Assert(ok, Equals, true)
+
+	_, ok = row.Value("city")
+	c.Assert(ok, Equals, false)
+
+	_, ok = row.Value("missing")
+	c.Assert(ok, Equals, false)
+}
+
+func (s *MySuite) TestFailuresSummaryOfOneFailure(c *C) {
+	message, stackTrace := Failures{{Message: "expected", StackTrace: "at step"}}.Summary()
+
+	c.Assert(message, Equals, "expected")
+	c.Assert(stackTrace, Equals, "at step")
+}
+
+func (s *MySuite) TestFailuresSummaryOfMultipleFailures(c *C) {
+	message, stackTrace := Failures{{Message: "expected", StackTrace: "at step"}, {Message: "teardown"}}.Summary()
+
+	c.Assert(message, Equals, "Multiple failures")
+	c.Assert(stackTrace, Equals, "expected\nat step\n\nteardown")
+}
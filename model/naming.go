@@ -0,0 +1,126 @@
+/*----------------------------------------------------------------
+ *  Copyright (c) ThoughtWorks, Inc.
+ *  Licensed under the Apache License, Version 2.0
+ *  See LICENSE in the project root for license information.
+ *----------------------------------------------------------------*/
+
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/getgauge/gauge-proto/go/gauge_messages"
+)
+
+// Placeholders available in scenario name templates.
+const (
+	headingPlaceholder           = "{heading}"
+	idPlaceholder                = "{id}"
+	specRowPlaceholder           = "{specRow}"
+	scenarioRowPlaceholder       = "{scenarioRow}"
+	specRowValuesPlaceholder     = "{specRowValues}"
+	scenarioRowValuesPlaceholder = "{scenarioRowValues}"
+)
+
+// columnPrefix starts a "{column:<header>}" placeholder, replaced by the
+// value of the column in the scenario table row, or else in the spec table row.
+const columnPrefix = "{column:"
+
+// placeholder matches the placeholders of a template, and names which are
+// not placeholders, which are kept.
+var placeholder = regexp.MustCompile(`\{column:[^}]+\}|\{[A-Za-z]+\}`)
+
+func newTableRow(table *gauge_messages.ProtoTable, rowIndex int) *TableRow {
+	row := &TableRow{Index: rowIndex, Headers: table.GetHeaders().GetCells()}
+	if rows := table.GetRows(); rowIndex >= 0 && rowIndex < len(rows) {
+		row.Cells = rows[rowIndex].GetCells()
+	}
+	return row
+}
+
+// getScenarioName names a scenario using the configured template.
+func (b *builder) getScenarioName(scenario *gauge_messages.ProtoScenario) string {
+	if b.options.ScenarioNameTemplate == "" {
+		return scenario.GetScenarioHeading()
+	}
+	return renderName(b.options.ScenarioNameTemplate, scenario, nil, nil)
+}
+
+// getTableDrivenScenarioName names a table driven scenario. Without a
+// template, the name is "<heading> | SpecRow: <n>: [<header>: <value>] ...
+// ScnRow: <n>: ..."; with StableNames the values are left out so that the
+// name survives edits to the data.
+func (b *builder) getTableDrivenScenarioName(scenario *gauge_messages.ProtoScenario, specRow, scenarioRow *TableRow) string {
+	if b.options.TableScenarioNameTemplate != "" {
+		return renderName(b.options.TableScenarioNameTemplate, scenario, specRow, scenarioRow)
+	}
+	var tableValues strings.Builder
+	if specRow != nil {
+		fmt.Fprintf(&tableValues, " SpecRow: %d", specRow.Index+1)
+		if !b.options.StableNames {
+			fmt.Fprintf(&tableValues, ": %s", strings.Join(buildHeaderValues(specRow), " "))
+		}
+	}
+	if scenarioRow != nil {
+		fmt.Fprintf(&tableValues, " ScnRow: %d", scenarioRow.Index+1)
+		if !b.options.StableNames {
+			fmt.Fprintf(&tableValues, ": %s", strings.Join(buildHeaderValues(scenarioRow), " "))
+		}
+	}
+	return scenario.GetScenarioHeading() + " |" + tableValues.String()
+}
+
+// renderName replaces the placeholders of the template in a single pass, so
+// that placeholders within the substituted values, e.g. a heading containing
+// "{column:x}", are kept as they are.
+func renderName(template string, scenario *gauge_messages.ProtoScenario, specRow, scenarioRow *TableRow) string {
+	rowIndex := func(row *TableRow) string {
+		if row == nil {
+			return ""
+		}
+		return fmt.Sprint(row.Index + 1)
+	}
+	values := map[string]string{
+		headingPlaceholder:           scenario.GetScenarioHeading(),
+		idPlaceholder:                scenario.GetID(),
+		specRowPlaceholder:           rowIndex(specRow),
+		scenarioRowPlaceholder:       rowIndex(scenarioRow),
+		specRowValuesPlaceholder:     strings.Join(buildHeaderValues(specRow), " "),
+		scenarioRowValuesPlaceholder: strings.Join(buildHeaderValues(scenarioRow), " "),
+	}
+	name := placeholder.ReplaceAllStringFunc(template, func(p string) string {
+		if value, ok := values[p]; ok {
+			return value
+		}
+		if !strings.HasPrefix(p, columnPrefix) {
+			return p
+		}
+		header := strings.TrimSuffix(strings.TrimPrefix(p, columnPrefix), "}")
+		for _, row := range []*TableRow{scenarioRow, specRow} {
+			if row == nil {
+				continue
+			}
+			if value, ok := row.Value(header); ok {
+				return value
+			}
+		}
+		return ""
+	})
+	return strings.TrimSpace(name)
+}
+
+// Builds "[Header: Value]" pairs for the given row.
+func buildHeaderValues(row *TableRow) []string {
+	var headerValues []string
+	if row == nil {
+		return headerValues
+	}
+	for i, header := range row.Headers {
+		if i < len(row.Cells) {
+			headerValues = append(headerValues, fmt.Sprintf("[%s: %s]", header, row.Cells[i]))
+		}
+	}
+	return headerValues
+}
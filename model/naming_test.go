@@ -0,0 +1,74 @@
+/*----------------------------------------------------------------
+ *  Copyright (c) ThoughtWorks, Inc.
+ *  Licensed under the Apache License, Version 2.0
+ *  See LICENSE in the project root for license information.
+ *----------------------------------------------------------------*/
+
+package model
+
+import (
+	"github.com/getgauge/gauge-proto/go/gauge_messages"
+	. "gopkg.in/check.v1"
+)
+
+var namingSpecTable = &gauge_messages.ProtoTable{
+	Headers: &gauge_messages.ProtoTableRow{Cells: []string{"name", "age"}},
+	Rows:    []*gauge_messages.ProtoTableRow{{Cells: []string{"john", "20"}}, {Cells: []string{"mike", "22"}}},
+}
+
+var namingScenarioTable = &gauge_messages.ProtoTable{
+	Headers: &gauge_messages.ProtoTableRow{Cells: []string{"city", "name"}},
+	Rows:    []*gauge_messages.ProtoTableRow{{Cells: []string{"London", "jane"}}},
+}
+
+func (s *MySuite) TestGetTableDrivenScenarioNameByDefault(c *C) {
+	scenario := &gauge_messages.ProtoScenario{ScenarioHeading: "Scenario"}
+	b := &builder{options: Options{}}
+
+	got := b.getTableDrivenScenarioName(scenario, newTableRow(namingSpecTable, 1), newTableRow(namingScenarioTable, 0))
+
+	c.Assert(got, Equals, "Scenario | SpecRow: 2: [name: mike] [age: 22] ScnRow: 1: [city: London] [name: jane]")
+}
+
+func (s *MySuite) TestGetTableDrivenScenarioNameWithStableNames(c *C) {
+	scenario := &gauge_messages.ProtoScenario{ScenarioHeading: "Scenario"}
+	b := &builder{options: Options{StableNames: true}}
+
+	got := b.getTableDrivenScenarioName(scenario, newTableRow(namingSpecTable, 1), newTableRow(namingScenarioTable, 0))
+
+	c.Assert(got, Equals, "Scenario | SpecRow: 2 ScnRow: 1")
+}
+
+func (s *MySuite) TestGetTableDrivenScenarioNameWithTemplate(c *C) {
+	scenario := &gauge_messages.ProtoScenario{ScenarioHeading: "Scenario", ID: "login.spec:4"}
+	b := &builder{options: Options{TableScenarioNameTemplate: "{heading} [{specRow}/{scenarioRow}] {column:name} {column:age} {column:missing} ({id})"}}
+
+	got := b.getTableDrivenScenarioName(scenario, newTableRow(namingSpecTable, 0), newTableRow(namingScenarioTable, 0))
+
+	c.Assert(got, Equals, "Scenario [1/1] jane 20  (login.spec:4)")
+}
+
+func (s *MySuite) TestGetTableDrivenScenarioNameWithRowValuesTemplate(c *C) {
+	scenario := &gauge_messages.ProtoScenario{ScenarioHeading: "Scenario"}
+	b := &builder{options: Options{TableScenarioNameTemplate: "{heading}: {specRowValues}{scenarioRowValues}"}}
+
+	got := b.getTableDrivenScenarioName(scenario, newTableRow(namingSpecTable, 0), nil)
+
+	c.Assert(got, Equals, "Scenario: [name: john] [age: 20]")
+}
+
+func (s *MySuite) TestGetScenarioNameWithTemplate(c *C) {
+	scenario := &gauge_messages.ProtoScenario{ScenarioHeading: "Scenario", ID: "login.spec:4"}
+
+	c.Assert((&builder{options: Options{}}).getScenarioName(scenario), Equals, "Scenario")
+	c.Assert((&builder{options: Options{ScenarioNameTemplate: "{id} {heading}"}}).getScenarioName(scenario), Equals, "login.spec:4 Scenario")
+}
+
+func (s *MySuite) TestGetTableDrivenScenarioNameKeepsPlaceholdersInValues(c *C) {
+	scenario := &gauge_messages.ProtoScenario{ScenarioHeading: "Pay {column:name} {id}", ID: "pay.spec:4"}
+	b := &builder{options: Options{TableScenarioNameTemplate: "{heading} | {column:name} {unknown}"}}
+
+	got := b.getTableDrivenScenarioName(scenario, newTableRow(namingSpecTable, 0), nil)
+
+	c.Assert(got, Equals, "Pay {column:name} {id} | john {unknown}")
+}
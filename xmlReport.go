@@ -18,16 +18,18 @@ import (
 	"github.com/getgauge/common"
 	"github.com/getgauge/gauge-proto/go/gauge_messages"
 	"github.com/getgauge/xml-report/builder"
+	"github.com/getgauge/xml-report/model"
 )
 
 const (
 	defaultReportsDir           = "reports"
 	gaugeReportsDirEnvName      = "gauge_reports_dir" // directory where reports are generated by plugins
+	gaugeScreenshotsDirEnvName  = "gauge_screenshots_dir"
 	executionAction             = "execution"
 	pluginActionEnv             = "xml-report_action"
 	xmlReport                   = "xml-report"
 	overwriteReportsEnvProperty = "overwrite_reports"
-	resultFile                  = "result.xml"
+	resultFileName              = "result"
 	timeFormat                  = "2006-01-02 15.04.05"
 )
 
@@ -36,18 +38,35 @@ var pluginDir string
 
 func createReport(suiteResult *gauge_messages.SuiteExecutionResult) {
 	dir := createReportsDirectory()
-	bytes, err := builder.NewXmlBuilder(0).GetXmlContent(suiteResult)
-	if err != nil {
-		logger.Fatal("Report generation failed: %s \n", err)
+	config := getBuilderConfig()
+	config.ReportDir = dir
+	report := model.NewReport(suiteResult, config.ModelOptions())
+	formats := getReportFormats()
+	written := 0
+	for _, format := range formats {
+		encoder, err := builder.NewEncoder(format, config)
+		if err != nil {
+			logger.Error("Skipping report format: %s\n", err)
+			continue
+		}
+		bytes, err := encoder.Encode(report)
+		if err != nil {
+			logger.Fatal("Report generation failed: %s \n", err)
+		}
+		err = writeResultFile(dir, resultFileName+encoder.FileExtension(), bytes)
+		if err != nil {
+			logger.Fatal("Report generation failed: %s \n", err)
+		}
+		copyAttachments(dir, encoder.Attachments())
+		written++
 	}
-	err = writeResultFile(dir, bytes)
-	if err != nil {
-		logger.Fatal("Report generation failed: %s \n", err)
+	if written == 0 {
+		logger.Fatal("Report generation failed: none of the report formats %s is known\n", strings.Join(formats, ","))
 	}
 	logger.Info("Successfully generated xml-report to => %s\n", dir)
 }
 
-func writeResultFile(reportDir string, bytes []byte) error {
+func writeResultFile(reportDir, resultFile string, bytes []byte) error {
 	resultPath := filepath.Join(reportDir, resultFile)
 	err := os.WriteFile(resultPath, bytes, common.NewFilePermissions)
 	if err != nil {
@@ -56,6 +75,21 @@ func writeResultFile(reportDir string, bytes []byte) error {
 	return nil
 }
 
+func copyAttachments(reportDir string, attachments []builder.Attachment) {
+	screenshotsDir := os.Getenv(gaugeScreenshotsDirEnvName)
+	for _, attachment := range attachments {
+		src := attachment.File
+		if !filepath.IsAbs(src) {
+			src = filepath.Join(screenshotsDir, attachment.File)
+		}
+		dest := filepath.Join(reportDir, filepath.FromSlash(attachment.Path))
+		createDirectory(filepath.Dir(dest))
+		if err := common.CopyFile(src, dest); err != nil {
+			logger.Error("Failed to copy attachment %s: %s\n", src, err)
+		}
+	}
+}
+
 func findPluginAndProjectRoot() {
 	projectRoot = os.Getenv(common.GaugeProjectRootEnv)
 	if projectRoot == "" {